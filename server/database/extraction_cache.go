@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"easy-apply/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const extractionCacheCollection = "ExtractionCache"
+
+// FirestoreExtractionCache persists document extraction results in Firestore under
+// each user's document, keyed by content hash. The entries hold resume text, so they
+// carry an expiresAt field: configure a TTL policy on it for the ExtractionCache
+// collection group so expired entries are deleted in the background.
+type FirestoreExtractionCache struct {
+	client *firestore.Client
+}
+
+// NewFirestoreExtractionCache creates an extraction cache backed by the given Firestore client.
+func NewFirestoreExtractionCache(client *firestore.Client) *FirestoreExtractionCache {
+	return &FirestoreExtractionCache{client: client}
+}
+
+func (c *FirestoreExtractionCache) doc(userID, key string) *firestore.DocumentRef {
+	return c.client.Collection("Users").Doc(userID).Collection(extractionCacheCollection).Doc(key)
+}
+
+// Get returns the user's cached extraction for key, or nil if there is none.
+// An expired entry is deleted and treated as a miss.
+func (c *FirestoreExtractionCache) Get(ctx context.Context, userID, key string) (*models.CachedExtraction, error) {
+	span := sentry.StartSpan(ctx, "db.get_extraction_cache")
+	defer span.Finish()
+	span.SetData("cache_key", key)
+
+	if c.client == nil {
+		return nil, errors.New("Firestore client not initialized")
+	}
+
+	ref := c.doc(userID, key)
+	snap, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return nil, fmt.Errorf("failed to read extraction cache: %w", err)
+	}
+
+	var entry models.CachedExtraction
+	if err := snap.DataTo(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode extraction cache entry: %w", err)
+	}
+	// TTL deletion can lag by a day or more, so expired entries are removed here too
+	if !time.Now().Before(entry.ExpiresAt) {
+		span.SetData("expired", true)
+		if _, err := ref.Delete(ctx); err != nil {
+			return nil, fmt.Errorf("failed to delete expired extraction cache entry: %w", err)
+		}
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Set stores an extraction result for the user under key, replacing any existing entry.
+func (c *FirestoreExtractionCache) Set(ctx context.Context, userID, key string, entry *models.CachedExtraction) error {
+	span := sentry.StartSpan(ctx, "db.set_extraction_cache")
+	defer span.Finish()
+	span.SetData("cache_key", key)
+
+	if c.client == nil {
+		return errors.New("Firestore client not initialized")
+	}
+
+	if _, err := c.doc(userID, key).Set(ctx, entry); err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return fmt.Errorf("failed to write extraction cache: %w", err)
+	}
+	return nil
+}
@@ -6,18 +6,60 @@ import (
 	"easy-apply/utils"  // For utils.ExtractSourceFromURL and utils.Logger
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	statusProcessing = "processing" // Consider moving to a common constants file if used elsewhere
-	statusCompleted  = "completed"
+	statusProcessing           = "processing" // Consider moving to a common constants file if used elsewhere
+	statusAwaitingConfirmation = "awaiting_confirmation"
+	statusCompleted            = "completed"
+
+	// jobDetailsPlaceholder stands in for the job title and company until generation completes
+	jobDetailsPlaceholder = "Processing..."
 )
 
+// ErrExtractionNotPending is returned when a history record is not waiting for the user to confirm its extracted text.
+var ErrExtractionNotPending = errors.New("history record is not awaiting extraction confirmation")
+
+// ErrHistoryRecordNotFound is returned when a history record does not exist.
+var ErrHistoryRecordNotFound = errors.New("history record not found")
+
+// ErrCoverLetterInputsMissing is returned when a history record has no resume or job posting to write a cover letter from.
+var ErrCoverLetterInputsMissing = errors.New("history record has no resume and job posting for a cover letter")
+
+// Cover letter version sources
+const (
+	CoverLetterSourceUpload     = "upload"
+	CoverLetterSourceStandalone = "cover_letter"
+)
+
+// CoverLetterInputs holds what a standalone cover letter is written from.
+type CoverLetterInputs struct {
+	ResumeText     string
+	JobPostingText string
+	JobTitle       string
+	JobCompany     string
+}
+
+// PendingExtraction holds the extracted inputs saved for a history record awaiting user confirmation.
+type PendingExtraction struct {
+	ResumeText     string
+	JobPostingText string
+	JobLink        string
+	JobFields      models.JobPostingFields
+	// Edited is set when the user corrected the extracted resume text
+	Edited bool
+}
+
 // CreateHistoryRecord creates an initial history record in Firestore.
-func CreateHistoryRecord(ctx context.Context, client *firestore.Client, userID, historyID, webLink string) error {
+// fileType is the format detected from the uploaded file's content.
+func CreateHistoryRecord(ctx context.Context, client *firestore.Client, userID, historyID, webLink string, fileType utils.FileType) error {
 	span := sentry.StartSpan(ctx, "db.create_history_record")
 	defer span.Finish()
 	span.SetTag("user_id", userID)
@@ -33,12 +75,14 @@ func CreateHistoryRecord(ctx context.Context, client *firestore.Client, userID,
 		"timestamp": firestore.ServerTimestamp,
 		"status":    statusProcessing,
 		"original": map[string]interface{}{
-			"resumePath": "",
-			"jobLink":    webLink,
+			"resumePath":     "",
+			"jobLink":        webLink,
+			"fileType":       fileType.Extension,
+			"resumeMimeType": fileType.MIMEType,
 		},
 		"jobDetails": map[string]interface{}{
-			"title":   "Processing...",
-			"company": "Processing...",
+			"title":   jobDetailsPlaceholder,
+			"company": jobDetailsPlaceholder,
 			"source":  utils.ExtractSourceFromURL(webLink),
 		},
 		"createdAt": firestore.ServerTimestamp,
@@ -54,8 +98,8 @@ func CreateHistoryRecord(ctx context.Context, client *firestore.Client, userID,
 	return nil
 }
 
-// UpdateHistoryRecord updates an existing history record in Firestore.
-func UpdateHistoryRecord(ctx context.Context, client *firestore.Client, historyRef *firestore.DocumentRef, extractedResume, processedResume, processedCoverLetter string, jobDetails map[string]string) error {
+// UpdateHistoryRecord saves the generated documents and how they were generated on an existing history record.
+func UpdateHistoryRecord(ctx context.Context, client *firestore.Client, historyRef *firestore.DocumentRef, result *models.GenerationResult) error {
 	span := sentry.StartSpan(ctx, "db.update_history_record")
 	defer span.Finish()
 	span.SetData("history_ref_path", historyRef.Path)
@@ -66,17 +110,24 @@ func UpdateHistoryRecord(ctx context.Context, client *firestore.Client, historyR
 
 	updates := []firestore.Update{
 		{Path: "status", Value: statusCompleted},
-		{Path: "original.resumeText", Value: extractedResume},
+		{Path: "original.resumeText", Value: result.SourceResume},
 		{Path: "generated", Value: map[string]interface{}{
-			"resumeText":      processedResume,
-			"coverLetterText": processedCoverLetter,
+			"resumeText":      result.Resume,
+			"coverLetterText": result.CoverLetter,
 		}},
-		{Path: "jobDetails.title", Value: jobDetails["title"]},
-		{Path: "jobDetails.company", Value: jobDetails["company"]},
+		{Path: "jobDetails.title", Value: result.JobTitle},
+		{Path: "jobDetails.company", Value: result.JobCompany},
+		{Path: "redaction", Value: result.Redaction},
+		{Path: "promptVersions", Value: result.PromptVersions},
+		{Path: "fabrication", Value: result.Fabrication},
+		{Path: "languages", Value: result.Languages},
 		{Path: "completedAt", Value: firestore.ServerTimestamp},
 	}
-	if source, ok := jobDetails["source"]; ok && source != "" {
-		updates = append(updates, firestore.Update{Path: "jobDetails.source", Value: source})
+	if result.JobSource != "" {
+		updates = append(updates, firestore.Update{Path: "jobDetails.source", Value: result.JobSource})
+	}
+	if result.ExtractionMethod != "" {
+		updates = append(updates, firestore.Update{Path: "jobDetails.extractionMethod", Value: result.ExtractionMethod})
 	}
 
 	_, err := historyRef.Update(ctx, updates)
@@ -89,6 +140,237 @@ func UpdateHistoryRecord(ctx context.Context, client *firestore.Client, historyR
 	return nil
 }
 
+// SaveExtractionResult stores the extracted resume, scraped job posting, its structured fields and the
+// extraction report on a history record. When awaitingConfirmation is set the record is parked until the user confirms or corrects the resume text.
+func SaveExtractionResult(ctx context.Context, client *firestore.Client, historyRef *firestore.DocumentRef, extractedResume, jobPosting string, jobFields models.JobPostingFields, report models.ExtractionReport, awaitingConfirmation bool) error {
+	span := sentry.StartSpan(ctx, "db.save_extraction_result")
+	defer span.Finish()
+	span.SetData("history_ref_path", historyRef.Path)
+	span.SetData("awaiting_confirmation", awaitingConfirmation)
+
+	if client == nil {
+		return errors.New("Firestore client not initialized for update")
+	}
+
+	updates := []firestore.Update{
+		{Path: "original.resumeText", Value: extractedResume},
+		{Path: "original.jobPostingText", Value: jobPosting},
+		{Path: "extraction", Value: report},
+	}
+	// The description is already part of jobPostingText
+	jobFields.Description = ""
+	if jobFields != (models.JobPostingFields{}) {
+		updates = append(updates, firestore.Update{Path: "original.jobPostingFields", Value: jobFields})
+	}
+	if awaitingConfirmation {
+		updates = append(updates, firestore.Update{Path: "status", Value: statusAwaitingConfirmation})
+	}
+
+	_, err := historyRef.Update(ctx, updates)
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return fmt.Errorf("failed to save extraction result: %w", err)
+	}
+	return nil
+}
+
+// ConfirmPendingExtraction claims a history record that is awaiting extraction confirmation
+// and moves it to processing, in one transaction so that only one confirmation can start
+// generation. A non-empty correctedResume that differs from the saved text replaces it,
+// and the record notes that the user edited it. A record in any other state, including
+// one claimed by a concurrent confirmation, returns ErrExtractionNotPending.
+func ConfirmPendingExtraction(ctx context.Context, client *firestore.Client, historyRef *firestore.DocumentRef, correctedResume string) (*PendingExtraction, error) {
+	span := sentry.StartSpan(ctx, "db.confirm_pending_extraction")
+	defer span.Finish()
+	span.SetData("history_ref_path", historyRef.Path)
+
+	if client == nil {
+		return nil, errors.New("Firestore client not initialized")
+	}
+
+	var pending *PendingExtraction
+	err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(historyRef)
+		if status.Code(err) == codes.NotFound {
+			return ErrHistoryRecordNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load history record: %w", err)
+		}
+
+		var record struct {
+			Status   string `firestore:"status"`
+			Original struct {
+				ResumeText     string                  `firestore:"resumeText"`
+				JobPostingText string                  `firestore:"jobPostingText"`
+				JobLink        string                  `firestore:"jobLink"`
+				JobFields      models.JobPostingFields `firestore:"jobPostingFields"`
+			} `firestore:"original"`
+		}
+		if err := snap.DataTo(&record); err != nil {
+			return fmt.Errorf("failed to decode history record: %w", err)
+		}
+		if record.Status != statusAwaitingConfirmation {
+			return fmt.Errorf("%w (status: %s)", ErrExtractionNotPending, record.Status)
+		}
+
+		pending = &PendingExtraction{
+			ResumeText:     record.Original.ResumeText,
+			JobPostingText: record.Original.JobPostingText,
+			JobLink:        record.Original.JobLink,
+			JobFields:      record.Original.JobFields,
+		}
+		if corrected := strings.TrimSpace(correctedResume); corrected != "" && corrected != strings.TrimSpace(record.Original.ResumeText) {
+			pending.ResumeText = corrected
+			pending.Edited = true
+		}
+
+		return tx.Update(historyRef, []firestore.Update{
+			{Path: "status", Value: statusProcessing},
+			{Path: "extraction.confirmedAt", Value: firestore.ServerTimestamp},
+			{Path: "extraction.editedByUser", Value: pending.Edited},
+		})
+	})
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		switch {
+		case errors.Is(err, ErrExtractionNotPending):
+			span.Status = sentry.SpanStatusFailedPrecondition
+			return nil, err
+		case errors.Is(err, ErrHistoryRecordNotFound):
+			span.Status = sentry.SpanStatusNotFound
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to confirm extraction: %w", err)
+	}
+	span.SetData("resume_text_edited", pending.Edited)
+	return pending, nil
+}
+
+// GetCoverLetterInputs loads the resume, job posting and job details saved on a history record.
+// It returns ErrHistoryRecordNotFound when the record does not exist.
+func GetCoverLetterInputs(ctx context.Context, client *firestore.Client, historyRef *firestore.DocumentRef) (*CoverLetterInputs, error) {
+	span := sentry.StartSpan(ctx, "db.get_cover_letter_inputs")
+	defer span.Finish()
+	span.SetData("history_ref_path", historyRef.Path)
+
+	if client == nil {
+		return nil, errors.New("Firestore client not initialized")
+	}
+
+	snap, err := historyRef.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		span.Status = sentry.SpanStatusNotFound
+		return nil, ErrHistoryRecordNotFound
+	}
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return nil, fmt.Errorf("failed to load history record: %w", err)
+	}
+
+	var record struct {
+		Original struct {
+			ResumeText     string `firestore:"resumeText"`
+			JobPostingText string `firestore:"jobPostingText"`
+		} `firestore:"original"`
+		JobDetails struct {
+			Title   string `firestore:"title"`
+			Company string `firestore:"company"`
+		} `firestore:"jobDetails"`
+	}
+	if err := snap.DataTo(&record); err != nil {
+		return nil, fmt.Errorf("failed to decode history record: %w", err)
+	}
+	if record.Original.ResumeText == "" || record.Original.JobPostingText == "" {
+		span.Status = sentry.SpanStatusFailedPrecondition
+		return nil, ErrCoverLetterInputsMissing
+	}
+
+	inputs := &CoverLetterInputs{
+		ResumeText:     record.Original.ResumeText,
+		JobPostingText: record.Original.JobPostingText,
+	}
+	// An upload whose generation did not finish still has placeholder job details
+	if record.JobDetails.Title != jobDetailsPlaceholder {
+		inputs.JobTitle = record.JobDetails.Title
+	}
+	if record.JobDetails.Company != jobDetailsPlaceholder {
+		inputs.JobCompany = record.JobDetails.Company
+	}
+	return inputs, nil
+}
+
+// SaveCoverLetterVersion stores a cover letter as the next version in the record's CoverLetters
+// subcollection and makes it the record's current letter; the resume is left alone. The letter
+// generated with the resume is saved as version 1 first, so it is not lost. It returns the new version's ID and number.
+func SaveCoverLetterVersion(ctx context.Context, client *firestore.Client, historyRef *firestore.DocumentRef, version models.CoverLetterVersion) (string, int, error) {
+	span := sentry.StartSpan(ctx, "db.save_cover_letter_version")
+	defer span.Finish()
+	span.SetData("history_ref_path", historyRef.Path)
+
+	if client == nil {
+		return "", 0, errors.New("Firestore client not initialized for update")
+	}
+
+	versions := historyRef.Collection("CoverLetters")
+	versionRef := versions.NewDoc()
+	err := client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(historyRef)
+		if err != nil {
+			return fmt.Errorf("failed to load history record: %w", err)
+		}
+		var record struct {
+			Generated struct {
+				CoverLetterText string `firestore:"coverLetterText"`
+			} `firestore:"generated"`
+			CoverLetter struct {
+				VersionCount int `firestore:"versionCount"`
+			} `firestore:"coverLetter"`
+		}
+		if err := snap.DataTo(&record); err != nil {
+			return fmt.Errorf("failed to decode history record: %w", err)
+		}
+
+		count := record.CoverLetter.VersionCount
+		if count == 0 && record.Generated.CoverLetterText != "" {
+			count = 1
+			if err := tx.Create(versions.NewDoc(), models.CoverLetterVersion{
+				Version:   count,
+				Text:      record.Generated.CoverLetterText,
+				Source:    CoverLetterSourceUpload,
+				CreatedAt: time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+		count++
+		version.Version = count
+		if err := tx.Create(versionRef, version); err != nil {
+			return err
+		}
+		return tx.Update(historyRef, []firestore.Update{
+			{Path: "generated.coverLetterText", Value: version.Text},
+			{Path: "coverLetter.versionCount", Value: count},
+			{Path: "coverLetter.currentVersionId", Value: versionRef.ID},
+			{Path: "coverLetter.updatedAt", Value: firestore.ServerTimestamp},
+		})
+	})
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return "", 0, fmt.Errorf("failed to save cover letter version: %w", err)
+	}
+	span.SetData("version", version.Version)
+	return versionRef.ID, version.Version, nil
+}
+
 // UpdateUserRecommendation updates the user's profile with the latest job recommendation.
 func UpdateUserRecommendation(ctx context.Context, client *firestore.Client, userID string, recommendation models.RecommendationResult, filename string) error {
 	span := sentry.StartSpan(ctx, "db.update_user_recommendation")
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"easy-apply/models"
+	"easy-apply/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	jobPageCacheCollection = "JobPageCache"
+	// defaultJobPageCacheItems bounds the collection when no size is given
+	defaultJobPageCacheItems = 5000
+)
+
+// FirestoreJobPageCache persists scraped job postings in Firestore, keyed by normalised URL hash.
+// Entries past their expiresAt are treated as misses and deleted; Prune also removes them,
+// along with the oldest entries beyond the size bound. A TTL policy on expiresAt is optional.
+type FirestoreJobPageCache struct {
+	client   *firestore.Client
+	maxItems int
+}
+
+// NewFirestoreJobPageCache creates a job page cache backed by the given Firestore client,
+// holding at most maxItems pages once pruned. maxItems <= 0 uses the default bound.
+func NewFirestoreJobPageCache(client *firestore.Client, maxItems int) *FirestoreJobPageCache {
+	if maxItems <= 0 {
+		maxItems = defaultJobPageCacheItems
+	}
+	return &FirestoreJobPageCache{client: client, maxItems: maxItems}
+}
+
+// Get returns the cached job page for key, or nil if there is none or it has expired.
+func (c *FirestoreJobPageCache) Get(ctx context.Context, key string) (*models.CachedJobPage, error) {
+	span := sentry.StartSpan(ctx, "db.get_job_page_cache")
+	defer span.Finish()
+	span.SetData("cache_key", key)
+
+	if c.client == nil {
+		return nil, errors.New("Firestore client not initialized")
+	}
+
+	ref := c.client.Collection(jobPageCacheCollection).Doc(key)
+	snap, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return nil, fmt.Errorf("failed to read job page cache: %w", err)
+	}
+
+	var page models.CachedJobPage
+	if err := snap.DataTo(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode job page cache entry: %w", err)
+	}
+	if !time.Now().Before(page.ExpiresAt) {
+		span.SetData("expired", true)
+		if _, err := ref.Delete(ctx); err != nil {
+			return nil, fmt.Errorf("failed to delete expired job page cache entry: %w", err)
+		}
+		return nil, nil
+	}
+	return &page, nil
+}
+
+// Set stores a job page under key, replacing any existing entry.
+func (c *FirestoreJobPageCache) Set(ctx context.Context, key string, page *models.CachedJobPage) error {
+	span := sentry.StartSpan(ctx, "db.set_job_page_cache")
+	defer span.Finish()
+	span.SetData("cache_key", key)
+
+	if c.client == nil {
+		return errors.New("Firestore client not initialized")
+	}
+
+	if _, err := c.client.Collection(jobPageCacheCollection).Doc(key).Set(ctx, page); err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return fmt.Errorf("failed to write job page cache: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes expired pages and the least recently fetched pages beyond the
+// size bound, and returns how many were deleted.
+func (c *FirestoreJobPageCache) Prune(ctx context.Context) (int, error) {
+	span := sentry.StartSpan(ctx, "db.prune_job_page_cache")
+	defer span.Finish()
+
+	if c.client == nil {
+		return 0, errors.New("Firestore client not initialized")
+	}
+
+	coll := c.client.Collection(jobPageCacheCollection)
+	expired, err := coll.Where("expiresAt", "<=", time.Now()).Select().Documents(ctx).GetAll()
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return 0, fmt.Errorf("failed to list expired job pages: %w", err)
+	}
+	overflow, err := coll.OrderBy("fetchedAt", firestore.Desc).Offset(c.maxItems).Select().Documents(ctx).GetAll()
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return 0, fmt.Errorf("failed to list job pages beyond the size bound: %w", err)
+	}
+
+	refs := make(map[string]*firestore.DocumentRef, len(expired)+len(overflow))
+	for _, snap := range append(expired, overflow...) {
+		refs[snap.Ref.ID] = snap.Ref
+	}
+	if len(refs) == 0 {
+		return 0, nil
+	}
+
+	bw := c.client.BulkWriter(ctx)
+	for _, ref := range refs {
+		if _, err := bw.Delete(ref); err != nil {
+			bw.End()
+			return 0, fmt.Errorf("failed to queue job page deletion: %w", err)
+		}
+	}
+	bw.End()
+	span.SetData("expired", len(expired))
+	span.SetData("overflow", len(overflow))
+	return len(refs), nil
+}
+
+// PruneEvery runs Prune every interval until ctx is done. Failures are logged only.
+func (c *FirestoreJobPageCache) PruneEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := c.Prune(ctx)
+			if err != nil {
+				utils.Logger.Printf("Job page cache pruning failed: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				utils.Logger.Printf("Pruned %d job page cache entries", deleted)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"easy-apply/models"
+	"easy-apply/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	llmCacheCollection = "LLMCache"
+	// defaultLLMCacheItems bounds the collection when no size is given
+	defaultLLMCacheItems = 10000
+	// llmCacheTouchInterval limits how often a hit rewrites lastUsedAt
+	llmCacheTouchInterval = time.Hour
+)
+
+// FirestoreLLMCache persists validated LLM replies in Firestore, keyed by request hash.
+// Entries past their expiresAt are treated as misses and deleted; Prune also removes them,
+// along with the least recently used entries beyond the size bound. A TTL policy on
+// expiresAt is optional.
+type FirestoreLLMCache struct {
+	client   *firestore.Client
+	maxItems int
+}
+
+// NewFirestoreLLMCache creates an LLM response cache backed by the given Firestore client,
+// holding at most maxItems replies once pruned. maxItems <= 0 uses the default bound.
+func NewFirestoreLLMCache(client *firestore.Client, maxItems int) *FirestoreLLMCache {
+	if maxItems <= 0 {
+		maxItems = defaultLLMCacheItems
+	}
+	return &FirestoreLLMCache{client: client, maxItems: maxItems}
+}
+
+// Get returns the cached reply for key, or nil if there is none or it has expired.
+func (c *FirestoreLLMCache) Get(ctx context.Context, key string) (*models.CachedLLMResponse, error) {
+	span := sentry.StartSpan(ctx, "db.get_llm_cache")
+	defer span.Finish()
+	span.SetData("cache_key", key)
+
+	if c.client == nil {
+		return nil, errors.New("Firestore client not initialized")
+	}
+
+	ref := c.client.Collection(llmCacheCollection).Doc(key)
+	snap, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return nil, fmt.Errorf("failed to read LLM cache: %w", err)
+	}
+
+	var entry models.CachedLLMResponse
+	if err := snap.DataTo(&entry); err != nil {
+		return nil, fmt.Errorf("failed to decode LLM cache entry: %w", err)
+	}
+	now := time.Now()
+	if !now.Before(entry.ExpiresAt) {
+		span.SetData("expired", true)
+		if _, err := ref.Delete(ctx); err != nil {
+			return nil, fmt.Errorf("failed to delete expired LLM cache entry: %w", err)
+		}
+		return nil, nil
+	}
+	// The memory tier absorbs most hits, so only refresh stale recency
+	if now.Sub(entry.LastUsedAt) > llmCacheTouchInterval {
+		if _, err := ref.Update(ctx, []firestore.Update{{Path: "lastUsedAt", Value: now}}); err != nil {
+			utils.Logger.Printf("Failed to update LLM cache entry %s last use: %v", key, err)
+		}
+	}
+	return &entry, nil
+}
+
+// Set stores a reply under key, replacing any existing entry.
+func (c *FirestoreLLMCache) Set(ctx context.Context, key string, entry *models.CachedLLMResponse) error {
+	span := sentry.StartSpan(ctx, "db.set_llm_cache")
+	defer span.Finish()
+	span.SetData("cache_key", key)
+
+	if c.client == nil {
+		return errors.New("Firestore client not initialized")
+	}
+
+	stored := *entry
+	stored.LastUsedAt = time.Now()
+	if _, err := c.client.Collection(llmCacheCollection).Doc(key).Set(ctx, &stored); err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return fmt.Errorf("failed to write LLM cache: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes expired replies and the least recently used replies beyond the
+// size bound, and returns how many were deleted.
+func (c *FirestoreLLMCache) Prune(ctx context.Context) (int, error) {
+	span := sentry.StartSpan(ctx, "db.prune_llm_cache")
+	defer span.Finish()
+
+	if c.client == nil {
+		return 0, errors.New("Firestore client not initialized")
+	}
+
+	coll := c.client.Collection(llmCacheCollection)
+	expired, err := coll.Where("expiresAt", "<=", time.Now()).Select().Documents(ctx).GetAll()
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return 0, fmt.Errorf("failed to list expired LLM replies: %w", err)
+	}
+	overflow, err := coll.OrderBy("lastUsedAt", firestore.Desc).Offset(c.maxItems).Select().Documents(ctx).GetAll()
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		span.Status = sentry.SpanStatusAborted
+		return 0, fmt.Errorf("failed to list LLM replies beyond the size bound: %w", err)
+	}
+
+	refs := make(map[string]*firestore.DocumentRef, len(expired)+len(overflow))
+	for _, snap := range append(expired, overflow...) {
+		refs[snap.Ref.ID] = snap.Ref
+	}
+	if len(refs) == 0 {
+		return 0, nil
+	}
+
+	bw := c.client.BulkWriter(ctx)
+	for _, ref := range refs {
+		if _, err := bw.Delete(ref); err != nil {
+			bw.End()
+			return 0, fmt.Errorf("failed to queue LLM reply deletion: %w", err)
+		}
+	}
+	bw.End()
+	span.SetData("expired", len(expired))
+	span.SetData("overflow", len(overflow))
+	return len(refs), nil
+}
+
+// PruneEvery runs Prune every interval until ctx is done. Failures are logged only.
+func (c *FirestoreLLMCache) PruneEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := c.Prune(ctx)
+			if err != nil {
+				utils.Logger.Printf("LLM cache pruning failed: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				utils.Logger.Printf("Pruned %d LLM cache entries", deleted)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
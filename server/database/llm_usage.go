@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"easy-apply/models"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/api/iterator"
+)
+
+const llmUsageCollection = "LLMUsage"
+
+// FirestoreUsageLedger stores one document per LLM call in the LLMUsage collection.
+// Filtering by user, History record or run together with a date range needs a
+// composite index on that field and createdAt.
+type FirestoreUsageLedger struct {
+	client *firestore.Client
+}
+
+// NewFirestoreUsageLedger creates a usage ledger backed by the given Firestore client.
+func NewFirestoreUsageLedger(client *firestore.Client) *FirestoreUsageLedger {
+	return &FirestoreUsageLedger{client: client}
+}
+
+// Record appends a usage record to the ledger.
+func (l *FirestoreUsageLedger) Record(ctx context.Context, record models.LLMUsageRecord) error {
+	if l.client == nil {
+		return errors.New("Firestore client not initialized")
+	}
+	if _, _, err := l.client.Collection(llmUsageCollection).Add(ctx, record); err != nil {
+		return fmt.Errorf("failed to write LLM usage record: %w", err)
+	}
+	return nil
+}
+
+// List returns the records matching query, oldest first.
+func (l *FirestoreUsageLedger) List(ctx context.Context, query models.UsageQuery) ([]models.LLMUsageRecord, error) {
+	span := sentry.StartSpan(ctx, "db.list_llm_usage")
+	defer span.Finish()
+
+	if l.client == nil {
+		return nil, errors.New("Firestore client not initialized")
+	}
+
+	q := l.client.Collection(llmUsageCollection).
+		Where("createdAt", ">=", query.From).
+		Where("createdAt", "<", query.To)
+	if query.UserID != "" {
+		q = q.Where("userId", "==", query.UserID)
+	}
+	if query.HistoryID != "" {
+		q = q.Where("historyId", "==", query.HistoryID)
+	}
+	if query.RunID != "" {
+		q = q.Where("runId", "==", query.RunID)
+	}
+
+	var records []models.LLMUsageRecord
+	iter := q.OrderBy("createdAt", firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			span.SetTag("error", "true")
+			span.SetData("error_message", err.Error())
+			span.Status = sentry.SpanStatusAborted
+			return nil, fmt.Errorf("failed to query LLM usage: %w", err)
+		}
+		var record models.LLMUsageRecord
+		if err := snap.DataTo(&record); err != nil {
+			return nil, fmt.Errorf("failed to decode LLM usage record %s: %w", snap.Ref.ID, err)
+		}
+		records = append(records, record)
+	}
+	span.SetData("record_count", len(records))
+	return records, nil
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
-	"easy-apply/constants"
+	"easy-apply/processors"
+	"easy-apply/prompts"
+	"easy-apply/retry"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,24 +17,17 @@ import (
 	"time"
 
 	"github.com/getsentry/sentry-go"
-	"github.com/google/generative-ai-go/genai"
 	"github.com/joho/godotenv"
-	"google.golang.org/api/option"
 )
 
-const (
-	maxRetries        = 3
-	maxJSONRetries    = 3 // New constant for JSON validation retries
-	initialRetryDelay = 1 * time.Second
-	maxRetryDelay     = 10 * time.Second
-	llmApiTimeout     = 200 * time.Second
-	ocrSpaceTimeout   = 200 * time.Second
-)
+const ocrSpaceTimeout = 200 * time.Second
 
-// GeminiClient wraps the Gemini client and model for reuse
-type GeminiClient struct {
-	client *genai.Client
-	model  *genai.GenerativeModel
+// ocrRetryPolicy governs OCR.Space calls for image-only listings
+var ocrRetryPolicy = retry.Policy{
+	MaxAttempts:    3,
+	InitialBackoff: 1 * time.Second,
+	MaxBackoff:     10 * time.Second,
+	MaxRetryAfter:  30 * time.Second,
 }
 
 func init() {
@@ -62,155 +57,14 @@ type JobDescription struct {
 	Tags                   []string    `json:"tags"`
 	Industry               string      `json:"industry"`
 	Domain                 string      `json:"domain"`
+	// PromptVersion is the listing prompt the fields were parsed with
+	PromptVersion string `json:"-"`
+	// Language is the detected language of the listing, a processors.DetectLanguage code
+	Language string `json:"-"`
 }
 
-// InitializeGeminiClient creates and configures a Gemini client for reuse
-func InitializeGeminiClient(ctx context.Context) (*GeminiClient, error) {
-	// Validate Gemini API key
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		err := fmt.Errorf("GEMINI_API_KEY environment variable not set")
-		sentry.CaptureException(err)
-		log.Println(err.Error())
-		return nil, err
-	}
-
-	// Initialize Gemini client
-	log.Println("Initializing Gemini client")
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
-	if err != nil {
-		sentry.CaptureException(err)
-		log.Printf("Failed to create Gemini client: %v", err)
-		return nil, err
-	}
-
-	modelName := constants.GeminiModelName
-	model := client.GenerativeModel(modelName)
-
-	// Set the system instruction for the model
-	model.SystemInstruction = &genai.Content{
-		Parts: []genai.Part{genai.Text(constants.OpenRouterInstrunctions)},
-	}
-
-	// Configure generation parameters
-	model.GenerationConfig = genai.GenerationConfig{
-		Temperature:      genai.Ptr(float32(0.7)),
-		MaxOutputTokens:  genai.Ptr(int32(4096)),
-		ResponseMIMEType: "application/json",
-	}
-
-	return &GeminiClient{
-		client: client,
-		model:  model,
-	}, nil
-}
-
-// Close closes the Gemini client
-func (gc *GeminiClient) Close() {
-	if gc.client != nil {
-		gc.client.Close()
-	}
-}
-
-// generateContentWithJSONValidation generates content with JSON validation and retry logic
-func (gc *GeminiClient) generateContentWithJSONValidation(ctx context.Context, description string) (string, error) {
-	var lastErr error
-
-	for attempt := 1; attempt <= maxJSONRetries; attempt++ {
-		log.Printf("Gemini API attempt %d/%d", attempt, maxJSONRetries)
-
-		// Generate content
-		resp, err := gc.model.GenerateContent(ctx, genai.Text(description))
-		if err != nil {
-			lastErr = fmt.Errorf("gemini api error (attempt %d): %w", attempt, err)
-			log.Printf("Gemini API error (attempt %d): %v", attempt, err)
-
-			if attempt < maxJSONRetries {
-				// Wait before retrying for API errors
-				time.Sleep(time.Duration(attempt) * time.Second)
-				continue
-			}
-			return "", lastErr
-		}
-
-		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-			lastErr = fmt.Errorf("no candidates returned or content is empty (attempt %d)", attempt)
-			log.Printf("No candidates returned or content is empty (attempt %d)", attempt)
-
-			if attempt < maxJSONRetries {
-				time.Sleep(time.Duration(attempt) * time.Second)
-				continue
-			}
-			return "", lastErr
-		}
-
-		// Extract response content
-		var responseContent string
-		if text, ok := resp.Candidates[0].Content.Parts[0].(genai.Text); ok {
-			responseContent = string(text)
-		} else {
-			responseContent = fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-		}
-
-		log.Printf("Gemini response received (attempt %d), length: %d characters", attempt, len(responseContent))
-
-		// Validate JSON structure
-		if isValidJSON(responseContent) {
-			log.Printf("Valid JSON received on attempt %d", attempt)
-			return responseContent, nil
-		}
-
-		// Try to clean/fix the JSON
-		cleanedJSON := cleanJSONResponse(responseContent)
-		if isValidJSON(cleanedJSON) {
-			log.Printf("JSON cleaned and validated on attempt %d", attempt)
-			return cleanedJSON, nil
-		}
-
-		lastErr = fmt.Errorf("invalid JSON received from Gemini (attempt %d)", attempt)
-		log.Printf("Invalid JSON received from Gemini (attempt %d). Raw response: %s", attempt, responseContent)
-
-		if attempt < maxJSONRetries {
-			log.Printf("Retrying due to invalid JSON...")
-			time.Sleep(time.Duration(attempt) * time.Second)
-		}
-	}
-
-	// All attempts failed
-	finalErr := fmt.Errorf("failed to get valid JSON after %d attempts: %w", maxJSONRetries, lastErr)
-	sentry.CaptureException(finalErr)
-	return "", finalErr
-}
-
-// isValidJSON checks if the string is valid JSON
-func isValidJSON(str string) bool {
-	var js map[string]interface{}
-	return json.Unmarshal([]byte(str), &js) == nil
-}
-
-// cleanJSONResponse attempts to clean and fix common JSON issues
-func cleanJSONResponse(response string) string {
-	response = strings.TrimSpace(response)
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimPrefix(response, "```")
-	response = strings.TrimSuffix(response, "```")
-
-	// Trim whitespace
-	response = strings.TrimSpace(response)
-
-	// Find the first { and last } to extract just the JSON object
-	firstBrace := strings.Index(response, "{")
-	lastBrace := strings.LastIndex(response, "}")
-
-	if firstBrace != -1 && lastBrace != -1 && lastBrace > firstBrace {
-		response = response[firstBrace : lastBrace+1]
-	}
-
-	return response
-}
-
-// ParseJobDescription processes the job description string using the provided Gemini client.
-func ParseJobDescription(ctx context.Context, geminiClient *GeminiClient, description string) (*JobDescription, error) {
+// ParseJobDescription structures the job description with the listing parsing LLM task.
+func ParseJobDescription(ctx context.Context, llm *processors.LLMRouter, description string) (*JobDescription, error) {
 	log.Println("Starting job description processing")
 	defer sentry.Flush(2 * time.Second)
 
@@ -245,7 +99,7 @@ func ParseJobDescription(ctx context.Context, geminiClient *GeminiClient, descri
 	linkRegex := regexp.MustCompile(`^https?://`)
 	if linkRegex.MatchString(desc) {
 		log.Printf("Detected potential image URL: %s", desc)
-		descOCR, err := processImageFromURLWithRetry(desc)
+		descOCR, err := processImageFromURLWithRetry(ctx, desc)
 		if err != nil {
 			log.Printf("OCR processing failed after retries: %v, using LINK_FOUND as placeholder", err)
 			desc = "LINK_FOUND"
@@ -255,14 +109,27 @@ func ParseJobDescription(ctx context.Context, geminiClient *GeminiClient, descri
 		}
 	}
 
-	// Generate content with JSON validation and retry
-	responseContent, err := geminiClient.generateContentWithJSONValidation(ctx, desc)
+	// Listings are A/B assigned by their description, so a re-scraped listing keeps its prompt
+	system, err := prompts.Render(prompts.ListingSystem, description, nil)
 	if err != nil {
+		sentry.CaptureException(err)
+		return nil, err
+	}
+
+	// The router retries API errors and invalid JSON, and strips code fences
+	resp, err := llm.CompleteJSON(ctx, processors.LLMTaskListingParsing, processors.LLMRequest{
+		System:        system.Text,
+		Messages:      []processors.LLMMessage{{Role: processors.LLMRoleUser, Content: desc}},
+		PromptVersion: system.ID(),
+	})
+	if err != nil {
+		sentry.CaptureException(err)
 		log.Printf("Failed to generate valid JSON content: %v", err)
 		return nil, err
 	}
+	responseContent := resp.Text
 
-	log.Printf("Processing complete. Gemini Response length: %d characters", len(responseContent))
+	log.Printf("Processing complete. %s response length: %d characters (%d tokens)", resp.Provider, len(responseContent), resp.Usage.TotalTokens)
 
 	// Preprocess and unmarshal the response
 	processedData, err := PreprocessJobDescription([]byte(responseContent))
@@ -275,11 +142,14 @@ func ParseJobDescription(ctx context.Context, geminiClient *GeminiClient, descri
 
 	var jobDesc JobDescription
 	if err := json.Unmarshal(processedData, &jobDesc); err != nil {
-		unmarshalErr := fmt.Errorf("failed to parse structured job description from Gemini response: %w", err)
+		unmarshalErr := fmt.Errorf("failed to parse structured job description from LLM response: %w", err)
 		sentry.CaptureException(unmarshalErr)
 		log.Printf("Error unmarshalling responseContent: %v", unmarshalErr)
 		return nil, unmarshalErr
 	}
+	jobDesc.PromptVersion = resp.PromptVersion
+	// desc is the text that was parsed: the OCR output for image listings
+	jobDesc.Language = processors.DetectLanguage(desc)
 
 	return &jobDesc, nil
 }
@@ -432,19 +302,14 @@ func (jd *JobDescription) GetContactDetailsAsString() string {
 	}
 }
 
-// processImageFromURLWithRetry calls processImageFromURL with retry logic.
-func processImageFromURLWithRetry(imageURL string) (string, error) {
-	var resultText string
-	err := withRetry(maxRetries, initialRetryDelay, maxRetryDelay, func() error {
-		text, err := processImageFromURL(imageURL)
-		if err == nil {
-			resultText = text
-		}
-		return err
+// processImageFromURLWithRetry calls processImageFromURL under ocrRetryPolicy.
+func processImageFromURLWithRetry(ctx context.Context, imageURL string) (string, error) {
+	resultText, err := retry.Do(ctx, ocrRetryPolicy, "OCR "+imageURL, func(attempt int) (string, error) {
+		return processImageFromURL(ctx, imageURL)
 	})
 
 	if err != nil {
-		finalErr := fmt.Errorf("OCR processing failed for URL %s after %d attempts: %w", imageURL, maxRetries, err)
+		finalErr := fmt.Errorf("OCR processing failed for URL %s: %w", imageURL, err)
 		sentry.WithScope(func(scope *sentry.Scope) {
 			scope.SetTag("ocr_image_url", imageURL)
 			sentry.CaptureException(finalErr)
@@ -455,8 +320,9 @@ func processImageFromURLWithRetry(imageURL string) (string, error) {
 	return resultText, nil
 }
 
-// processImageFromURL performs OCR on an image URL.
-func processImageFromURL(imageURL string) (string, error) {
+// processImageFromURL performs OCR on an image URL. Failures that cannot
+// succeed on a retry are classified as such.
+func processImageFromURL(ctx context.Context, imageURL string) (string, error) {
 	log.Printf("Processing image URL with OCR: %s", imageURL)
 
 	apiKey := os.Getenv("OCRSPACE_API_KEY")
@@ -464,7 +330,7 @@ func processImageFromURL(imageURL string) (string, error) {
 		err := fmt.Errorf("OCRSPACE_API_KEY environment variable not set")
 		sentry.CaptureException(err)
 		log.Println(err.Error())
-		return "", err
+		return "", retry.NewError(retry.ClassInvalidRequest, err)
 	}
 
 	ocrAPIURL := "https://api.ocr.space/parse/image"
@@ -475,11 +341,11 @@ func processImageFromURL(imageURL string) (string, error) {
 	form.Add("isOverlayRequired", "false")
 
 	log.Println("Creating OCR API request")
-	req, err := http.NewRequest("POST", ocrAPIURL, strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", ocrAPIURL, strings.NewReader(form.Encode()))
 	if err != nil {
 		reqErr := fmt.Errorf("error creating OCR request for %s: %w", imageURL, err)
 		log.Println(reqErr.Error())
-		return "", reqErr
+		return "", retry.NewError(retry.ClassInvalidRequest, reqErr)
 	}
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
@@ -498,7 +364,7 @@ func processImageFromURL(imageURL string) (string, error) {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		statusErr := fmt.Errorf("OCR service returned error (status %d) for %s: %s", resp.StatusCode, imageURL, string(bodyBytes))
 		log.Println(statusErr.Error())
-		return "", statusErr
+		return "", retry.StatusError(resp, statusErr)
 	}
 
 	var result struct {
@@ -533,31 +399,7 @@ func processImageFromURL(imageURL string) (string, error) {
 
 	noTextErr := fmt.Errorf("no text found in the image or empty parsed text from OCR for URL: %s", imageURL)
 	log.Println(noTextErr.Error())
-	return "", noTextErr
-}
-
-// withRetry implements exponential backoff retry logic.
-func withRetry(maxAttempts int, initialDelay, maxDelay time.Duration, fn func() error) error {
-	var err error
-	delay := initialDelay
-
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		err = fn()
-		if err == nil {
-			return nil
-		}
-
-		log.Printf("Attempt %d/%d failed: %v", attempt, maxAttempts, err)
-		if attempt < maxAttempts {
-			log.Printf("Retrying in %v...", delay)
-			time.Sleep(delay)
-			delay *= 2
-			if delay > maxDelay {
-				delay = maxDelay
-			}
-		}
-	}
-	return fmt.Errorf("function failed after %d attempts: %w", maxAttempts, err)
+	return "", retry.NewError(retry.ClassInvalidRequest, noTextErr)
 }
 
 // min helper for time.Duration
@@ -12,6 +12,8 @@ import (
 	"net/http"
 	"os"
 	"time"
+
+	"easy-apply/retry"
 )
 
 var (
@@ -108,34 +110,36 @@ func (s *PDFService) ConvertHTMLToPDF(ctx context.Context, html string, options
 		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
 	}
 
-	// Create request with context
-	req, err := http.NewRequestWithContext(ctx, "POST", s.GotenbergURL, body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
 	s.Logger.Printf("Converting HTML to PDF with options: %+v", options)
 
-	resp, err := s.Client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
-	}
+	// Gotenberg restarts Chromium under load, so 503s and dropped connections are retried
+	return retry.Do(ctx, retry.Default, "Gotenberg conversion", func(attempt int) (io.ReadCloser, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", s.GotenbergURL, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, retry.NewError(retry.ClassInvalidRequest, fmt.Errorf("failed to create request: %w", err))
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		resp, err := s.Client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute request: %w", err)
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		s.Logger.Printf("Gotenberg error (status %d): %s", resp.StatusCode, string(bodyBytes))
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			s.Logger.Printf("Gotenberg error (status %d): %s", resp.StatusCode, string(bodyBytes))
 
-		switch resp.StatusCode {
-		case http.StatusServiceUnavailable:
-			return nil, ErrServiceUnavailable
-		default:
-			return nil, fmt.Errorf("%w: status %d", ErrGotenbergError, resp.StatusCode)
+			switch resp.StatusCode {
+			case http.StatusServiceUnavailable:
+				return nil, retry.StatusError(resp, ErrServiceUnavailable)
+			default:
+				return nil, retry.StatusError(resp, fmt.Errorf("%w: status %d", ErrGotenbergError, resp.StatusCode))
+			}
 		}
-	}
 
-	return resp.Body, nil
+		return resp.Body, nil
+	})
 }
 
 func (s *PDFService) getDefaultOptions() *PDFOptions {
@@ -42,5 +42,10 @@ func initFirebase() {
 
 	handlers.FirestoreClient = firestoreClient
 
+	handlers.AuthClient, err = app.Auth(context.Background())
+	if err != nil {
+		log.Fatalf("Error initializing Firebase Auth client: %v", err)
+	}
+
 	log.Println("Firebase services initialized successfully")
 }
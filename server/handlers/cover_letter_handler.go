@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"easy-apply/database"
+	"easy-apply/models"
+	"easy-apply/processors"
+	"easy-apply/services"
+	"easy-apply/sse"
+	"easy-apply/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// CoverLetterHandler writes a new cover letter for a completed upload without
+// touching its resume, and saves it as the record's next cover letter version.
+//
+// The request must carry the user's Firebase ID token as "Authorization: Bearer <token>".
+// Form fields: userId and historyId (required), channelId to stream the
+// letter over SSE, and the options tone (formal, warm, concise), length
+// (short, medium, long), language (a code or name; defaults to the job
+// posting's language), addressee and hooks (a JSON array of
+// company-specific points).
+func CoverLetterHandler(w http.ResponseWriter, r *http.Request) {
+	hub := sentry.CurrentHub().Clone()
+	ctx := sentry.SetHubOnContext(r.Context(), hub)
+	r = r.WithContext(ctx)
+
+	transaction := sentry.StartTransaction(ctx, fmt.Sprintf("http.handler.%s %s", r.Method, r.URL.Path), sentry.ContinueFromRequest(r))
+	defer transaction.Finish()
+
+	if r.Method != http.MethodPost {
+		utils.HandleError(w, r, "Method Not Allowed", http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		utils.HandleError(w, r, "Invalid form data", http.StatusBadRequest, err)
+		return
+	}
+
+	req := models.CoverLetterRequest{
+		UserID:    strings.TrimSpace(r.FormValue("userId")),
+		HistoryID: strings.TrimSpace(r.FormValue("historyId")),
+		ChannelID: r.FormValue("channelId"),
+		Options: models.CoverLetterOptions{
+			Tone:      r.FormValue("tone"),
+			Length:    r.FormValue("length"),
+			Language:  r.FormValue("language"),
+			Addressee: r.FormValue("addressee"),
+		},
+	}
+	if err := parseFormJSON(w, r, "hooks", &req.Options.Hooks); err != nil {
+		utils.HandleError(w, r, fmt.Sprintf("Invalid format for hooks: %v", err), http.StatusBadRequest, err)
+		return
+	}
+	if req.UserID == "" || req.HistoryID == "" {
+		err := errors.New("userId and historyId are required")
+		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	// The letter is built from the user's stored resume, so only that user may ask for one
+	if status, err := verifyUserToken(r, req.UserID); err != nil {
+		utils.HandleError(w, r, http.StatusText(status), status, err)
+		return
+	}
+	hub.Scope().SetUser(sentry.User{ID: req.UserID})
+	hub.Scope().SetTag("user_id", req.UserID)
+	hub.Scope().SetTag("history_id", req.HistoryID)
+
+	options, err := services.NormalizeCoverLetterOptions(req.Options)
+	if err != nil {
+		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+
+	historyRef := FirestoreClient.Collection("Users").Doc(req.UserID).Collection("History").Doc(req.HistoryID)
+	inputs, err := database.GetCoverLetterInputs(ctx, FirestoreClient, historyRef)
+	if errors.Is(err, database.ErrCoverLetterInputsMissing) {
+		utils.HandleError(w, r, "This upload has no resume and job posting to write a cover letter from", http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, database.ErrHistoryRecordNotFound) {
+		utils.HandleError(w, r, "Failed to load the upload", http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		utils.HandleError(w, r, "Failed to load the upload", http.StatusInternalServerError, err)
+		return
+	}
+
+	// Without a requested language the letter follows the job posting
+	languages, err := services.DetectLanguages(options.Language, inputs.JobPostingText, inputs.ResumeText)
+	if err != nil {
+		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	options.Language = languages.Output
+
+	ctx = processors.WithLLMUsageScope(ctx, processors.LLMUsageScope{
+		UserID:    req.UserID,
+		HistoryID: req.HistoryID,
+		Endpoint:  r.URL.Path,
+	})
+
+	var streamLetter services.DocumentDeltaFunc
+	if req.ChannelID != "" {
+		// Same pause as the upload handler so the SSE connection can register
+		time.Sleep(500 * time.Millisecond)
+		sse.SendProgress(req.ChannelID, "cover_letter", "active", "Writing your cover letter...")
+		streamLetter = func(delta models.DocumentDelta) {
+			sse.SendProgressWithData(req.ChannelID, "generation", "streaming", "", delta)
+		}
+	}
+
+	letter, promptVersion, err := services.GenerateCoverLetter(ctx, inputs.ResumeText, inputs.JobPostingText, inputs.JobTitle, inputs.JobCompany, options, streamLetter)
+	if err != nil {
+		sse.SendProgress(req.ChannelID, "cover_letter", "failed", "An error occurred while writing the cover letter.")
+		var schemaErr *processors.SchemaError
+		if errors.As(err, &schemaErr) {
+			utils.HandleError(w, r, "The AI service returned an unusable response. Please try again.", http.StatusBadGateway, err)
+			return
+		}
+		utils.HandleError(w, r, fmt.Sprintf("Cover letter generation failed: %v", err), http.StatusInternalServerError, err)
+		return
+	}
+
+	versionID, version, err := database.SaveCoverLetterVersion(ctx, FirestoreClient, historyRef, models.CoverLetterVersion{
+		Text:          letter,
+		Source:        database.CoverLetterSourceStandalone,
+		Options:       options,
+		PromptVersion: promptVersion,
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		sse.SendProgress(req.ChannelID, "cover_letter", "failed", "Failed to save the cover letter.")
+		utils.HandleError(w, r, "Failed to save the cover letter", http.StatusInternalServerError, err)
+		return
+	}
+	sse.SendProgress(req.ChannelID, "cover_letter", "complete", "Your cover letter is ready!")
+
+	utils.SendJSONResponse(w, r, models.CoverLetterResponse{
+		Success:     true,
+		HistoryID:   req.HistoryID,
+		VersionID:   versionID,
+		Version:     version,
+		CoverLetter: letter,
+		Options:     options,
+	}, http.StatusOK)
+}
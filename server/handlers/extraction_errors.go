@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"easy-apply/processors"
+	"errors"
+	"net/http"
+)
+
+// extractionErrorResponse maps typed document extraction and job link errors to a client
+// status code and message. ok is false for errors that are not the client's fault.
+func extractionErrorResponse(err error) (statusCode int, message string, ok bool) {
+	switch {
+	case errors.Is(err, processors.ErrEncryptedDocument):
+		return http.StatusUnprocessableEntity, "The uploaded document is password protected. Please remove the password and upload it again.", true
+	case errors.Is(err, processors.ErrTooManyPages):
+		return http.StatusRequestEntityTooLarge, "The uploaded document has too many pages. Please upload a shorter resume.", true
+	case errors.Is(err, processors.ErrDecompressedSizeExceeded):
+		return http.StatusRequestEntityTooLarge, "The uploaded document is too large to process.", true
+	case errors.Is(err, processors.ErrMalformedDocument):
+		return http.StatusUnprocessableEntity, "The uploaded document appears to be corrupted or malformed and could not be read.", true
+	case errors.Is(err, processors.ErrUnsupportedFormat):
+		return http.StatusUnsupportedMediaType, "Unsupported file type. Only PDF, DOCX, and TXT files are supported.", true
+	case errors.Is(err, processors.ErrExtractionTimeout):
+		return http.StatusUnprocessableEntity, "The uploaded document took too long to process. Please try a simpler file.", true
+	case errors.Is(err, processors.ErrUnsafeURL):
+		return http.StatusBadRequest, "The job posting link points to an address we cannot access. Please use a public job posting URL.", true
+	default:
+		return 0, "", false
+	}
+}
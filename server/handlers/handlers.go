@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"cloud.google.com/go/firestore"
+	"firebase.google.com/go/auth"
 )
 
 // set in main
@@ -10,6 +11,9 @@ var MaxUploadSize int64
 // set in firebase.go
 var FirestoreClient *firestore.Client
 
+// AuthClient verifies Firebase ID tokens; set in firebase.go
+var AuthClient *auth.Client
+
 type OCRResponse struct {
 	ParsedResults []struct {
 		ParsedText  string `json:"ParsedText"`
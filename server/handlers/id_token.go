@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors returned by verifyUserToken
+var (
+	errMissingIDToken = errors.New("missing Firebase ID token")
+	errUserMismatch   = errors.New("ID token does not belong to the requested user")
+)
+
+// verifyUserToken checks the Firebase ID token sent as "Authorization: Bearer <token>"
+// and that it was issued to userID. On failure it returns the status to respond with.
+func verifyUserToken(r *http.Request, userID string) (int, error) {
+	if AuthClient == nil {
+		return http.StatusInternalServerError, errors.New("Firebase Auth client not initialized")
+	}
+	header := r.Header.Get("Authorization")
+	idToken := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	if !strings.HasPrefix(header, "Bearer ") || idToken == "" {
+		return http.StatusUnauthorized, errMissingIDToken
+	}
+
+	token, err := AuthClient.VerifyIDToken(r.Context(), idToken)
+	if err != nil {
+		return http.StatusUnauthorized, fmt.Errorf("invalid Firebase ID token: %w", err)
+	}
+	if token.UID != userID {
+		return http.StatusForbidden, errUserMismatch
+	}
+	return http.StatusOK, nil
+}
@@ -4,13 +4,12 @@ import (
 	// "context"
 	"easy-apply/database"
 	"easy-apply/models"
+	"easy-apply/processors"
 	"easy-apply/services"
 	"easy-apply/utils"
 	"errors" // For direct error creation
 	"fmt"
 	"net/http"
-	"path/filepath"
-	"strings"
 
 	// "cloud.google.com/go/firestore" // FirestoreClient is now a package variable
 	"github.com/getsentry/sentry-go"
@@ -42,6 +41,10 @@ func JobRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
 	parseReqSpan := sentry.StartSpan(ctx, "parse.job_recommendation_request_handler")
 	req, err := parseJobRecommendationRequest(r) // r already has ctx
 	parseReqSpan.Finish()                        // Status set within parseJobRecommendationRequest if needed
+	if statusCode, message, ok := extractionErrorResponse(err); ok {
+		utils.HandleError(w, r, message, statusCode, err)
+		return
+	}
 	if err != nil {
 		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
 		return
@@ -85,7 +88,8 @@ func JobRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
 	resumeText := req.Resume
 
 	analyzeSpan := sentry.StartSpan(ctx, "logic.analyze_resume_for_recommendation_handler")
-	recommendation, err := services.AnalyzeResumeForRecommendation(ctx, resumeText)
+	usageCtx := processors.WithLLMUsageScope(ctx, processors.LLMUsageScope{UserID: req.UserID, Endpoint: r.URL.Path})
+	recommendation, err := services.AnalyzeResumeForRecommendation(usageCtx, resumeText)
 	analyzeSpan.Finish() // Status set in service
 	if err != nil {
 		utils.HandleError(w, r, "Failed to analyze resume for recommendations", http.StatusInternalServerError, err)
@@ -172,8 +176,18 @@ func parseJobRecommendationRequest(r *http.Request) (*models.JobRecommendationRe
 	span.SetData("resume_source", "file_upload")
 	span.SetData("uploaded_filename", handler.Filename)
 
+	fileReadSpan := sentry.StartSpan(ctx, "file.read_resume_content_bytes_inline_handler")
+	fileContent, err := services.ProcessFileContent(ctx, file, handler.Filename) // Use services.ProcessFileContent
+	fileReadSpan.Finish()                                                        // Status set in service
+	if err != nil {
+		span.Status = sentry.SpanStatusInternalError
+		return nil, fmt.Errorf("failed to read uploaded resume file: %w", err)
+	}
+
+	// The format is decided from the content; the filename is not checked first
 	fileTypeValidationSpan := sentry.StartSpan(ctx, "validation.resume_file_type_inline_handler")
-	if err := utils.ValidateFileType(handler.Filename); err != nil { // Use utils.ValidateFileType
+	fileType, err := utils.ValidateFileContent(handler.Filename, fileContent)
+	if err != nil {
 		fileTypeValidationSpan.SetTag("error", "true")
 		fileTypeValidationSpan.SetData("error_message", err.Error())
 		fileTypeValidationSpan.Status = sentry.SpanStatusInvalidArgument
@@ -182,18 +196,10 @@ func parseJobRecommendationRequest(r *http.Request) (*models.JobRecommendationRe
 		return nil, err
 	}
 	fileTypeValidationSpan.Finish()
+	span.SetData("detected_mime_type", fileType.MIMEType)
 
-	fileReadSpan := sentry.StartSpan(ctx, "file.read_resume_content_bytes_inline_handler")
-	fileContent, err := services.ProcessFileContent(ctx, file, handler.Filename) // Use services.ProcessFileContent
-	fileReadSpan.Finish()                                                        // Status set in service
-	if err != nil {
-		span.Status = sentry.SpanStatusInternalError
-		return nil, fmt.Errorf("failed to read uploaded resume file: %w", err)
-	}
-
-	fileExt := strings.ToLower(filepath.Ext(handler.Filename))
 	extractTextSpan := sentry.StartSpan(ctx, "file.extract_text_from_resume_inline_handler")
-	resumeTextFromFile, err := services.ExtractTextFromFile(ctx, fileContent, fileExt) // Use services.ExtractTextFromFile
+	resumeTextFromFile, _, err := services.ExtractTextFromFile(ctx, userID, fileContent, fileType.Extension) // Use services.ExtractTextFromFile
 	extractTextSpan.Finish()                                                           // Status set in service
 	if err != nil {
 		span.Status = sentry.SpanStatusInternalError
@@ -3,12 +3,13 @@ package handlers
 import (
 	"easy-apply/database"
 	"easy-apply/models"
+	"easy-apply/processors"
 	"easy-apply/services"
 	"easy-apply/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -42,6 +43,12 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A historyId means the client is confirming text extracted by an earlier upload
+	if strings.TrimSpace(r.FormValue("historyId")) != "" {
+		handleConfirmedUpload(w, r)
+		return
+	}
+
 	handleFileUpload(w, r)
 }
 
@@ -78,6 +85,16 @@ func handleFileUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := processors.ValidatePublicURL(ctx, webLink); err != nil {
+		statusCode, message, ok := extractionErrorResponse(err)
+		if !ok {
+			statusCode, message = http.StatusBadRequest, "The job posting link could not be resolved. Please check the link."
+		}
+		sse.SendProgress(channelID, "upload", "failed", message)
+		utils.HandleError(w, r, message, statusCode, err)
+		return
+	}
+
 	file, handler, err := r.FormFile("file")
 	if err != nil {
 		sse.SendProgress(channelID, "upload", "failed", "Could not read file from form.")
@@ -86,47 +103,129 @@ func handleFileUpload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	if err := utils.ValidateFileType(handler.Filename); err != nil {
-		sse.SendProgress(channelID, "upload", "failed", "Invalid file type. Please use PDF or DOCX.")
-		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
+	// The format is decided from the content, not the filename, so a
+	// renamed file gets a specific message instead of a blanket rejection
+	fileContent, err := services.ProcessFileContent(ctx, file, handler.Filename)
+	if err != nil {
+		sse.SendProgress(channelID, "upload", "failed", "Failed to read file content: "+err.Error())
+		utils.HandleError(w, r, "Failed to read content from uploaded file", http.StatusInternalServerError, err)
 		return
 	}
 
-	sse.SendProgress(channelID, "upload", "complete", "File validated successfully.")
-	sse.SendProgress(channelID, "processing", "active", "Extracting content from resume and job posting...")
-
-	fileContent, err := services.ProcessFileContent(ctx, file, handler.Filename)
+	fileType, err := utils.ValidateFileContent(handler.Filename, fileContent)
 	if err != nil {
-		sse.SendProgress(channelID, "processing", "failed", "Failed to read file content: "+err.Error())
-		utils.HandleError(w, r, "Failed to read content from uploaded file", http.StatusInternalServerError, err)
+		sse.SendProgress(channelID, "upload", "failed", err.Error())
+		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
 		return
 	}
+	span.SetData("detected_mime_type", fileType.MIMEType)
+
+	sse.SendProgress(channelID, "upload", "complete", "File validated successfully.")
+	sse.SendProgress(channelID, "processing", "active", "Extracting content from resume and job posting...")
 
 	historyID := uuid.New().String()
 	hub.Scope().SetTag("history_id", historyID)
 
-	if err := database.CreateHistoryRecord(ctx, FirestoreClient, userID, historyID, webLink); err != nil {
+	if err := database.CreateHistoryRecord(ctx, FirestoreClient, userID, historyID, webLink, fileType); err != nil {
 		sse.SendProgress(channelID, "processing", "failed", "Database error occurred.")
 		utils.HandleError(w, r, "Failed to create initial history record", http.StatusInternalServerError, err)
 		return
 	}
 
-	fileExt := strings.ToLower(filepath.Ext(handler.Filename))
-	processingResult, err := services.ProcessFileAndWeb(ctx, fileContent, fileExt, webLink)
+	refreshJobPage := r.FormValue("refreshJobPosting") == "true"
+	processingResult, err := services.ProcessFileAndWeb(ctx, userID, fileContent, fileType.Extension, webLink, refreshJobPage)
+	if statusCode, message, ok := extractionErrorResponse(err); ok {
+		sse.SendProgress(channelID, "processing", "failed", message)
+		utils.HandleError(w, r, message, statusCode, err)
+		return
+	}
 	if err != nil {
 		sse.SendProgress(channelID, "processing", "failed", "Error during file/web processing: "+err.Error())
 		utils.HandleError(w, r, fmt.Sprintf("Error during file/web processing: %v", err), http.StatusInternalServerError, err)
 		return
 	}
 
-	sse.SendProgress(channelID, "processing", "complete", "Content extraction successful.")
+	report := processingResult.ExtractionReport
+	report.MIMEType = fileType.MIMEType
+	span.SetData("extraction_quality_score", report.QualityScore)
+	sse.SendProgressWithData(channelID, "processing", "complete", "Content extraction successful.", report)
 
 	historyRef := FirestoreClient.Collection("Users").Doc(userID).Collection("History").Doc(historyID)
-	sendOpenAIAnalysisAndRespond(w, r, processingResult.ScrappedWebJobPosting, processingResult.ExtractedResume, handler.Filename, historyRef, channelID)
+	confirmExtraction := r.FormValue("confirmExtraction") == "true"
+	if err := database.SaveExtractionResult(ctx, FirestoreClient, historyRef, processingResult.ExtractedResume, processingResult.ScrappedWebJobPosting, processingResult.JobPostingFields, report, confirmExtraction); err != nil {
+		sse.SendProgress(channelID, "processing", "failed", "Database error occurred.")
+		utils.HandleError(w, r, "Failed to save extraction result", http.StatusInternalServerError, err)
+		return
+	}
+
+	// Let the user review and correct the extracted text before paying for generation
+	if confirmExtraction {
+		sse.SendProgressWithData(channelID, "review", "active", "Please review the text extracted from your resume.", report)
+		utils.SendJSONResponse(w, r, models.ExtractionReviewResponse{
+			Success:          true,
+			HistoryID:        historyID,
+			ResumeText:       processingResult.ExtractedResume,
+			ExtractionReport: report,
+		}, http.StatusOK)
+		return
+	}
+
+	sendOpenAIAnalysisAndRespond(w, r, processingResult.ScrappedWebJobPosting, processingResult.JobPostingFields, processingResult.ExtractedResume, handler.Filename, webLink, historyRef, channelID)
 }
 
-func sendOpenAIAnalysisAndRespond(w http.ResponseWriter, r *http.Request, jobPosting, extractedResume, filename string, historyRef *firestore.DocumentRef, channelID string) {
+// handleConfirmedUpload resumes an upload that was parked for extraction review,
+// using the resume text as confirmed or corrected by the user.
+func handleConfirmedUpload(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	hub := sentry.GetHubFromContext(ctx)
+	span := sentry.StartSpan(ctx, "function.handleConfirmedUpload")
+	defer span.Finish()
+
+	channelID := r.FormValue("channelId")
+	userID := strings.TrimSpace(r.FormValue("userId"))
+	historyID := strings.TrimSpace(r.FormValue("historyId"))
+	hub.Scope().SetTag("history_id", historyID)
+
+	// Same pause as handleFileUpload so the SSE connection can register
+	time.Sleep(500 * time.Millisecond)
+
+	if userID == "" {
+		err := fmt.Errorf("user ID is required")
+		sse.SendProgress(channelID, "review", "failed", "Invalid request data: "+err.Error())
+		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+
+	historyRef := FirestoreClient.Collection("Users").Doc(userID).Collection("History").Doc(historyID)
+	// Claiming the record is atomic, so a repeated or concurrent confirmation gets a 409
+	pending, err := database.ConfirmPendingExtraction(ctx, FirestoreClient, historyRef, r.FormValue("resumeText"))
+	if errors.Is(err, database.ErrExtractionNotPending) {
+		sse.SendProgress(channelID, "review", "failed", "This upload has already been processed.")
+		utils.HandleError(w, r, "This upload is not awaiting confirmation", http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, database.ErrHistoryRecordNotFound) {
+		sse.SendProgress(channelID, "review", "failed", "Could not find the pending upload.")
+		utils.HandleError(w, r, "Failed to load pending upload", http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		sse.SendProgress(channelID, "review", "failed", "Database error occurred.")
+		utils.HandleError(w, r, "Failed to confirm extraction", http.StatusInternalServerError, err)
+		return
+	}
+	span.SetData("resume_text_edited", pending.Edited)
+	sse.SendProgress(channelID, "review", "complete", "Resume text confirmed.")
+
+	sendOpenAIAnalysisAndRespond(w, r, pending.JobPostingText, pending.JobFields, pending.ResumeText, "", pending.JobLink, historyRef, channelID)
+}
+
+func sendOpenAIAnalysisAndRespond(w http.ResponseWriter, r *http.Request, jobPosting string, jobFields models.JobPostingFields, extractedResume, filename, webLink string, historyRef *firestore.DocumentRef, channelID string) {
+	ctx := processors.WithLLMUsageScope(r.Context(), processors.LLMUsageScope{
+		UserID:    historyRef.Parent.Parent.ID,
+		HistoryID: historyRef.ID,
+		Endpoint:  r.URL.Path,
+	})
 	span := sentry.StartSpan(ctx, "function.sendOpenAIAnalysisAndRespond")
 	defer span.Finish()
 
@@ -146,8 +245,38 @@ func sendOpenAIAnalysisAndRespond(w http.ResponseWriter, r *http.Request, jobPos
 		return
 	}
 
-	processedDocs, jobDetails, err := services.ProcessWithOpenAI(ctx, jobPosting, extractedResume, selectedTemplate.HTMLContent, selectedColors)
+	streamDocuments := func(delta models.DocumentDelta) {
+		sse.SendProgressWithData(channelID, "generation", "streaming", "", delta)
+	}
+	// Without an outputLanguage the documents follow the job posting's language
+	languages, err := services.DetectLanguages(r.FormValue("outputLanguage"), jobPosting, extractedResume)
+	if err != nil {
+		sse.SendProgress(channelID, "analysis", "failed", "Unsupported output language.")
+		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	span.SetData("output_language", languages.Output)
+
+	// strictFacts asks for unsupported claims to be revised or stripped rather than only flagged
+	strictFacts := r.FormValue("strictFacts") == "true"
+	result, err := services.ProcessWithOpenAI(ctx, services.GenerationOptions{
+		JobPosting:   jobPosting,
+		Resume:       extractedResume,
+		TemplateHTML: selectedTemplate.HTMLContent,
+		Colors:       selectedColors,
+		KnownJob:     jobFields,
+		Languages:    languages,
+		StrictFacts:  strictFacts,
+		OnDelta:      streamDocuments,
+	})
 	if err != nil {
+		var schemaErr *processors.SchemaError
+		if errors.As(err, &schemaErr) {
+			// The model kept returning unusable output; retrying later may succeed
+			sse.SendProgress(channelID, "analysis", "failed", "The AI service returned an unusable response. Please try again.")
+			utils.HandleError(w, r, "The AI service returned an unusable response. Please try again.", http.StatusBadGateway, err)
+			return
+		}
 		sse.SendProgress(channelID, "analysis", "failed", "An error occurred during AI processing: "+err.Error())
 		utils.HandleError(w, r, fmt.Sprintf("OpenAI processing failed: %v", err), http.StatusInternalServerError, err)
 		return
@@ -156,11 +285,11 @@ func sendOpenAIAnalysisAndRespond(w http.ResponseWriter, r *http.Request, jobPos
 	sse.SendProgress(channelID, "analysis", "complete", "AI tailoring complete.")
 	sse.SendProgress(channelID, "finalizing", "active", "Finalizing and saving documents...")
 
-	if extractedSource, ok := jobDetails["source"]; !ok || extractedSource == "" {
-		jobDetails["source"] = utils.ExtractSourceFromURL(r.FormValue("weblink"))
+	if result.JobSource == "" {
+		result.JobSource = utils.ExtractSourceFromURL(webLink)
 	}
 
-	if err := database.UpdateHistoryRecord(ctx, FirestoreClient, historyRef, extractedResume, processedDocs["resume"], processedDocs["coverLetter"], jobDetails); err != nil {
+	if err := database.UpdateHistoryRecord(ctx, FirestoreClient, historyRef, result); err != nil {
 		sse.SendProgress(channelID, "finalizing", "failed", "Failed to save the generated documents: "+err.Error())
 		utils.HandleError(w, r, "Failed to update history record after OpenAI processing", http.StatusInternalServerError, err)
 		return
@@ -173,11 +302,12 @@ func sendOpenAIAnalysisAndRespond(w http.ResponseWriter, r *http.Request, jobPos
 
 	response := models.UploadResponse{
 		Success:     true,
-		Resume:      processedDocs["resume"],
-		CoverLetter: processedDocs["coverLetter"],
+		Resume:      result.Resume,
+		CoverLetter: result.CoverLetter,
 		HistoryID:   historyRef.ID,
-		JobTitle:    jobDetails["title"],
-		JobCompany:  jobDetails["company"],
+		JobTitle:    result.JobTitle,
+		JobCompany:  result.JobCompany,
+		Fabrication: result.Fabrication,
 	}
 	utils.SendJSONResponse(w, r, response, http.StatusOK)
 }
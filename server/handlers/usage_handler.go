@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"easy-apply/models"
+	"easy-apply/processors"
+	"easy-apply/services"
+	"easy-apply/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultUsageReportDays is the report window when no from date is given
+const defaultUsageReportDays = 30
+
+// UsageReportResponse is the body returned by UsageReportHandler. Cache holds
+// this instance's response cache counters since it started.
+type UsageReportResponse struct {
+	From    string                                         `json:"from"`
+	To      string                                         `json:"to"`
+	GroupBy []string                                       `json:"groupBy"`
+	Rows    []models.UsageSummaryRow                       `json:"rows"`
+	Cache   map[processors.LLMTask]processors.CacheMetrics `json:"cache"`
+}
+
+// UsageReportHandler aggregates the LLM usage ledger. It requires the
+// USAGE_REPORT_TOKEN bearer token and is disabled when that is unset.
+//
+// Query parameters: from and to (YYYY-MM-DD, to is inclusive; defaults to
+// the last 30 days), groupBy (comma-separated day, user, task, model;
+// defaults to day,task,model) and optional userId, historyId and runId filters.
+func UsageReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.HandleError(w, r, "Method Not Allowed", http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	token := os.Getenv("USAGE_REPORT_TOKEN")
+	provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		utils.HandleError(w, r, "Forbidden", http.StatusForbidden, errors.New("invalid or missing usage report token"))
+		return
+	}
+
+	q := r.URL.Query()
+	to := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if value := q.Get("to"); value != "" {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			utils.HandleError(w, r, "Invalid 'to' date, expected YYYY-MM-DD", http.StatusBadRequest, err)
+			return
+		}
+		to = day.Add(24 * time.Hour)
+	}
+	from := to.AddDate(0, 0, -defaultUsageReportDays)
+	if value := q.Get("from"); value != "" {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			utils.HandleError(w, r, "Invalid 'from' date, expected YYYY-MM-DD", http.StatusBadRequest, err)
+			return
+		}
+		from = day
+	}
+	if !from.Before(to) {
+		utils.HandleError(w, r, "'from' must not be after 'to'", http.StatusBadRequest, fmt.Errorf("from %s is after to %s", from, to))
+		return
+	}
+
+	groupBy := []string{services.UsageGroupDay, services.UsageGroupTask, services.UsageGroupModel}
+	if value := q.Get("groupBy"); value != "" {
+		groupBy = nil
+		for _, dimension := range strings.Split(value, ",") {
+			if dimension = strings.TrimSpace(dimension); dimension != "" {
+				groupBy = append(groupBy, dimension)
+			}
+		}
+	}
+
+	query := models.UsageQuery{
+		From:      from,
+		To:        to,
+		UserID:    q.Get("userId"),
+		HistoryID: q.Get("historyId"),
+		RunID:     q.Get("runId"),
+	}
+	rows, err := services.SummarizeUsage(r.Context(), query, groupBy)
+	if errors.Is(err, services.ErrInvalidUsageGroup) {
+		utils.HandleError(w, r, err.Error(), http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		utils.HandleError(w, r, "Failed to build usage report", http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SendJSONResponse(w, r, UsageReportResponse{
+		From:    from.Format("2006-01-02"),
+		To:      to.Add(-24 * time.Hour).Format("2006-01-02"),
+		GroupBy: groupBy,
+		Rows:    rows,
+		Cache:   processors.LLMCacheMetrics(),
+	}, http.StatusOK)
+}
@@ -1,21 +1,26 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
+
+	"easy-apply/processors"
 )
 
 // LinkResult represents the result of a link validation
 type LinkResult struct {
-	Valid      bool   `json:"valid"`
-	Status     int    `json:"status,omitempty"`
-	URL        string `json:"url,omitempty"`
-	Reason     string `json:"reason,omitempty"`
-	RedirectTo string `json:"redirectTo,omitempty"`
-	Error      string `json:"error,omitempty"`
+	Valid       bool                     `json:"valid"`
+	Verdict     processors.LinkVerdict   `json:"verdict"`
+	Status      int                      `json:"status,omitempty"`
+	URL         string                   `json:"url,omitempty"`
+	Reason      string                   `json:"reason,omitempty"`
+	RedirectTo  string                   `json:"redirectTo,omitempty"`
+	Redirects   []processors.RedirectHop `json:"redirects,omitempty"`
+	ContentType string                   `json:"contentType,omitempty"`
+	Error       string                   `json:"error,omitempty"`
 }
 
 func ValidateURLHandler(w http.ResponseWriter, r *http.Request) {
@@ -27,7 +32,7 @@ func ValidateURLHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate the URL
-	result := IsValidLink(url)
+	result := IsValidLink(r.Context(), url)
 
 	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
@@ -43,95 +48,36 @@ func ValidateURLHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// IsValidLink checks if a URL is valid by making an HTTP HEAD request
-func IsValidLink(url string) LinkResult {
-	// Add protocol if missing
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		url = "https://" + url
-	}
-
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Don't follow redirects - we want to detect them
-			return http.ErrUseLastResponse
-		},
-	}
-
-	// Make HEAD request to avoid downloading full content
-	resp, err := client.Head(url)
-	if err != nil {
-		return LinkResult{
-			Valid:  false,
-			Error:  err.Error(),
-			Reason: "Request failed: Please check the Link",
-		}
-	}
-	defer resp.Body.Close()
-
-	// Check if response is successful (2xx status codes)
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return LinkResult{
-			Valid:  true,
-			Status: resp.StatusCode,
-			URL:    resp.Request.URL.String(),
-		}
-	}
-
-	// Check if it's a redirect (3xx status codes)
-	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
-		location := resp.Header.Get("Location")
-		return LinkResult{
-			Valid:      false,
-			Status:     resp.StatusCode,
-			Reason:     "Redirect detected: The link redirects to another URL",
-			RedirectTo: location,
-		}
-	}
+// linkChecker is shared by link checks so robots.txt rules and connections are reused
+var linkChecker = processors.NewWebProcessor("")
 
-	// Other error status codes (4xx, 5xx)
-	return LinkResult{
-		Valid:  false,
-		Status: resp.StatusCode,
-		Reason: fmt.Sprintf("The website is not supported: HTTP %d error", resp.StatusCode),
-	}
-}
+// linkCheckTimeout bounds a link check including retries and redirects
+const linkCheckTimeout = 30 * time.Second
 
-// IsValidLinkWithFollowRedirect checks validity but allows following redirects
-func IsValidLinkWithFollowRedirect(url string) LinkResult {
+// IsValidLink checks a job link, following a bounded redirect chain, and
+// reports a verdict. Only live links are valid.
+func IsValidLink(ctx context.Context, url string) LinkResult {
 	// Add protocol if missing
 	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 		url = "https://" + url
 	}
 
-	// Create HTTP client with timeout (default redirect behavior)
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	ctx, cancel := context.WithTimeout(ctx, linkCheckTimeout)
+	defer cancel()
+
+	health := linkChecker.CheckLink(ctx, url)
+	result := LinkResult{
+		Valid:       health.Verdict == processors.LinkLive,
+		Verdict:     health.Verdict,
+		Status:      health.Status,
+		URL:         health.FinalURL,
+		Reason:      health.Reason,
+		Redirects:   health.Redirects,
+		ContentType: health.ContentType,
+		Error:       health.Error,
 	}
-
-	resp, err := client.Head(url)
-	if err != nil {
-		return LinkResult{
-			Valid:  false,
-			Error:  err.Error(),
-			Reason: "Request failed",
-		}
-	}
-	defer resp.Body.Close()
-
-	// Check if final response is successful
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return LinkResult{
-			Valid:  true,
-			Status: resp.StatusCode,
-			URL:    resp.Request.URL.String(),
-		}
-	}
-
-	return LinkResult{
-		Valid:  false,
-		Status: resp.StatusCode,
-		Reason: fmt.Sprintf("HTTP %d error", resp.StatusCode),
+	if len(health.Redirects) > 0 {
+		result.RedirectTo = health.FinalURL
 	}
+	return result
 }
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"easy-apply/database"
+	"easy-apply/prompts"
 	"easy-apply/services"
 	"easy-apply/utils"
 	"fmt"
@@ -58,7 +60,16 @@ func main() {
 		Timeout:         3 * time.Second,
 	})
 
+	// Fail fast on a broken prompt template rather than on the first request
+	if err := prompts.Initialize(); err != nil {
+		sentry.CaptureException(err)
+		utils.Logger.Fatalf("Failed to load prompt templates: %v", err)
+	}
+
 	initFirebase()
+	llmCache := database.NewFirestoreLLMCache(firestoreClient, 0)
+	services.InitializeLLMCache(llmCache)
+	go llmCache.PruneEvery(ctx, time.Hour)
 	setupRoutes(sentryHandler)
 
 	go launchScraper(ctx)
@@ -71,6 +82,11 @@ func main() {
 	// Initialize services that depend on these processors
 	services.InitializeFileService(fileProc)     // Pass the initialized FileProcessor
 	services.InitializeOpenAIService(openAIProc) // Pass the initialized OpenAIProcessor
+	services.InitializeExtractionCache(database.NewFirestoreExtractionCache(firestoreClient))
+	jobPageCache := database.NewFirestoreJobPageCache(firestoreClient, 0)
+	services.InitializeJobPageCache(jobPageCache)
+	go jobPageCache.PruneEvery(ctx, time.Hour)
+	services.InitializeUsageLedger(database.NewFirestoreUsageLedger(firestoreClient))
 	// webProc is used by services.ProcessFileAndWeb, which uses the package-level webProcessor
 
 	port := os.Getenv("PORT")
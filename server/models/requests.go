@@ -7,3 +7,12 @@ type JobRecommendationRequest struct {
 	RequestType string `json:"requestType"` // "new", "saved" (for recommendation based on saved profile)
 	Filename    string `json:"filename"`    // Original filename if uploaded
 }
+
+// CoverLetterRequest asks for a new cover letter for a completed upload. The
+// resume and job posting are taken from the history record.
+type CoverLetterRequest struct {
+	UserID    string             `json:"userId"`
+	HistoryID string             `json:"historyId"`
+	ChannelID string             `json:"channelId,omitempty"` // SSE channel to stream the letter to
+	Options   CoverLetterOptions `json:"options"`
+}
@@ -22,5 +22,26 @@ type UploadResponse struct {
 	HistoryID   string `json:"historyId"`
 	JobTitle    string `json:"jobTitle"`
 	JobCompany  string `json:"jobCompany"`
-	Error       string `json:"error,omitempty"`
+	// Fabrication lists resume claims the original resume does not support
+	Fabrication FabricationReport `json:"fabrication"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// ExtractionReviewResponse is returned by /upload when the client asks to confirm
+// the extracted resume text before generation is run.
+type ExtractionReviewResponse struct {
+	Success          bool             `json:"success"`
+	HistoryID        string           `json:"historyId"`
+	ResumeText       string           `json:"resumeText"`
+	ExtractionReport ExtractionReport `json:"extractionReport"`
+}
+
+// CoverLetterResponse is returned by /cover-letter with the new letter and the version it was saved as.
+type CoverLetterResponse struct {
+	Success     bool               `json:"success"`
+	HistoryID   string             `json:"historyId"`
+	VersionID   string             `json:"versionId"`
+	Version     int                `json:"version"`
+	CoverLetter string             `json:"coverLetter"`
+	Options     CoverLetterOptions `json:"options"`
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // RecommendationResult holds the outcome of a resume analysis for job recommendations.
 type RecommendationResult struct {
 	Industry   string `json:"industry"`
@@ -11,7 +13,9 @@ type RecommendationResult struct {
 // ProcessingResult holds the outcome of concurrent file and web processing.
 type ProcessingResult struct {
 	ExtractedResume       string
+	ExtractionReport      ExtractionReport
 	ScrappedWebJobPosting string
+	JobPostingFields      JobPostingFields
 	Error                 error
 }
 
@@ -33,3 +37,201 @@ type Colors struct {
 	Secondary string `json:"secondary"`
 	Text      string `json:"text"`
 }
+
+// RedactionEntry records one PII value that was replaced before an LLM call.
+// The original value is never stored, only a truncated HMAC of it under a
+// server-side key, and only when that key is configured.
+type RedactionEntry struct {
+	Type        string `json:"type" firestore:"type"`
+	Placeholder string `json:"placeholder" firestore:"placeholder"`
+	ValueHash   string `json:"valueHash,omitempty" firestore:"valueHash,omitempty"`
+	Occurrences int    `json:"occurrences" firestore:"occurrences"`
+}
+
+// RedactionAudit summarises what was redacted from a document before it was sent to an LLM provider.
+type RedactionAudit struct {
+	Enabled bool             `json:"enabled" firestore:"enabled"`
+	Entries []RedactionEntry `json:"entries" firestore:"entries"`
+}
+
+// FabricationFlag is a claim in a generated resume that the original resume does not support.
+type FabricationFlag struct {
+	Type     string `json:"type" firestore:"type"` // ORGANIZATION, JOB_TITLE, DATE, DEGREE or NUMBER
+	Claim    string `json:"claim" firestore:"claim"`
+	Context  string `json:"context" firestore:"context"`
+	Stripped bool   `json:"stripped" firestore:"stripped"` // removed from the resume in strict mode
+}
+
+// FabricationReport is the result of checking a generated resume against the original.
+// In strict mode a resume with flags is regenerated once and any claims still
+// unsupported are stripped where that can be done safely.
+type FabricationReport struct {
+	Enabled     bool              `json:"enabled" firestore:"enabled"`
+	Strict      bool              `json:"strict" firestore:"strict"`
+	Regenerated bool              `json:"regenerated" firestore:"regenerated"`
+	Flags       []FabricationFlag `json:"flags" firestore:"flags"`
+}
+
+// CoverLetterOptions controls how a standalone cover letter is written.
+// Empty fields take the service defaults.
+type CoverLetterOptions struct {
+	Tone      string   `json:"tone" firestore:"tone"`           // "formal", "warm" or "concise"
+	Length    string   `json:"length" firestore:"length"`       // "short", "medium" or "long"
+	Language  string   `json:"language" firestore:"language"`   // a code or name, e.g. "fr" or "French"
+	Addressee string   `json:"addressee" firestore:"addressee"` // e.g. "Ms. Phiri, HR Manager"
+	Hooks     []string `json:"hooks" firestore:"hooks"`         // company-specific points to work in
+}
+
+// CoverLetterVersion is one saved cover letter of a history record. Version 1
+// is the letter generated with the resume when there was one.
+type CoverLetterVersion struct {
+	Version       int                `json:"version" firestore:"version"`
+	Text          string             `json:"text" firestore:"text"`
+	Source        string             `json:"source" firestore:"source"` // "upload" or "cover_letter"
+	Options       CoverLetterOptions `json:"options" firestore:"options"`
+	PromptVersion string             `json:"promptVersion" firestore:"promptVersion"`
+	CreatedAt     time.Time          `json:"createdAt" firestore:"createdAt"`
+}
+
+// LanguageInfo records the languages detected in an upload and the language its documents were
+// generated in, as codes from processors.DetectLanguage ("und" when unknown).
+type LanguageInfo struct {
+	Posting string `json:"posting" firestore:"posting"`
+	Resume  string `json:"resume" firestore:"resume"`
+	Output  string `json:"output" firestore:"output"`
+}
+
+// GenerationResult is a tailored resume and cover letter together with what
+// is recorded about how they were generated.
+type GenerationResult struct {
+	// SourceResume is the resume text the documents were generated from
+	SourceResume string
+	Resume       string
+	CoverLetter  string
+	JobTitle     string
+	JobCompany   string
+	// JobSource is the job site, and ExtractionMethod how the page's job fields were found
+	JobSource        string
+	ExtractionMethod string
+	Redaction        RedactionAudit
+	// PromptVersions maps each LLM task run to the prompt versions it used
+	PromptVersions map[string]string
+	Fabrication    FabricationReport
+	Languages      LanguageInfo
+}
+
+// DocumentDelta is a piece of a generated document streamed to the client
+// while generation is still running. Offset is the number of characters of
+// the document already sent; Reset means previously sent text for the
+// document should be discarded before appending Text.
+type DocumentDelta struct {
+	Document string `json:"document"` // "resume" or "coverLetter"
+	Offset   int    `json:"offset"`
+	Text     string `json:"text"`
+	Reset    bool   `json:"reset,omitempty"`
+}
+
+// ExtractionReport describes how well text was extracted from an uploaded document.
+type ExtractionReport struct {
+	Format       string   `json:"format" firestore:"format"`
+	MIMEType     string   `json:"mimeType,omitempty" firestore:"mimeType,omitempty"`
+	Pages        int      `json:"pages" firestore:"pages"`
+	Characters   int      `json:"characters" firestore:"characters"`
+	OCRUsed      bool     `json:"ocrUsed" firestore:"ocrUsed"`
+	Language     string   `json:"language" firestore:"language"`
+	QualityScore float64  `json:"qualityScore" firestore:"qualityScore"` // 0 (unusable) to 1 (clean text)
+	Warnings     []string `json:"warnings,omitempty" firestore:"warnings,omitempty"`
+}
+
+// CachedExtraction is a user's stored document extraction result, keyed by a hash of the file content.
+type CachedExtraction struct {
+	Text             string           `firestore:"text"`
+	Report           ExtractionReport `firestore:"report"`
+	ExtractorVersion string           `firestore:"extractorVersion"`
+	CreatedAt        time.Time        `firestore:"createdAt"`
+	ExpiresAt        time.Time        `firestore:"expiresAt"`
+}
+
+// JobPostingFields holds structured details of a job posting found on its web page.
+type JobPostingFields struct {
+	Title          string `json:"title,omitempty" firestore:"title,omitempty"`
+	Company        string `json:"company,omitempty" firestore:"company,omitempty"`
+	Location       string `json:"location,omitempty" firestore:"location,omitempty"`
+	Deadline       string `json:"deadline,omitempty" firestore:"deadline,omitempty"`
+	DatePosted     string `json:"datePosted,omitempty" firestore:"datePosted,omitempty"`
+	EmploymentType string `json:"employmentType,omitempty" firestore:"employmentType,omitempty"`
+	Description    string `json:"description,omitempty" firestore:"description,omitempty"`
+	// ExtractionMethod is how the fields were found: json-ld, microdata or a site extractor name.
+	// It is not the job site, which comes from the posting URL.
+	ExtractionMethod string `json:"extractionMethod,omitempty" firestore:"extractionMethod,omitempty"`
+}
+
+// CachedJobPage is a scraped job posting stored for reuse across uploads of the same advert.
+type CachedJobPage struct {
+	URL          string           `firestore:"url"`
+	Text         string           `firestore:"text"`
+	Fields       JobPostingFields `firestore:"fields"`
+	ETag         string           `firestore:"etag,omitempty"`
+	LastModified string           `firestore:"lastModified,omitempty"`
+	FetchedAt    time.Time        `firestore:"fetchedAt"`
+	ValidatedAt  time.Time        `firestore:"validatedAt"`
+	ExpiresAt    time.Time        `firestore:"expiresAt"`
+}
+
+// CachedLLMResponse is a validated LLM reply stored for reuse by identical requests.
+type CachedLLMResponse struct {
+	Task          string    `json:"task" firestore:"task"`
+	Provider      string    `json:"provider" firestore:"provider"`
+	Model         string    `json:"model" firestore:"model"`
+	PromptVersion string    `json:"promptVersion,omitempty" firestore:"promptVersion,omitempty"`
+	Text          string    `json:"text" firestore:"text"`
+	CreatedAt     time.Time `json:"createdAt" firestore:"createdAt"`
+	ExpiresAt     time.Time `json:"expiresAt" firestore:"expiresAt"`
+	// LastUsedAt orders entries for eviction from the Firestore cache
+	LastUsedAt time.Time `json:"lastUsedAt,omitempty" firestore:"lastUsedAt,omitempty"`
+}
+
+// LLMUsageRecord is one LLM call in the usage ledger. UserID and HistoryID
+// are set for user requests, RunID for scraper runs.
+type LLMUsageRecord struct {
+	Task             string    `json:"task" firestore:"task"`
+	Provider         string    `json:"provider" firestore:"provider"`
+	Model            string    `json:"model" firestore:"model"`
+	PromptTokens     int       `json:"promptTokens" firestore:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens" firestore:"completionTokens"`
+	TotalTokens      int       `json:"totalTokens" firestore:"totalTokens"`
+	LatencyMs        int64     `json:"latencyMs" firestore:"latencyMs"`
+	CostUSD          float64   `json:"costUsd" firestore:"costUsd"`
+	Success          bool      `json:"success" firestore:"success"`
+	UserID           string    `json:"userId,omitempty" firestore:"userId,omitempty"`
+	HistoryID        string    `json:"historyId,omitempty" firestore:"historyId,omitempty"`
+	RunID            string    `json:"runId,omitempty" firestore:"runId,omitempty"`
+	Endpoint         string    `json:"endpoint,omitempty" firestore:"endpoint,omitempty"`
+	CreatedAt        time.Time `json:"createdAt" firestore:"createdAt"`
+}
+
+// UsageQuery selects ledger records created in [From, To), optionally for
+// one user, History record or scraper run
+type UsageQuery struct {
+	From      time.Time
+	To        time.Time
+	UserID    string
+	HistoryID string
+	RunID     string
+}
+
+// UsageSummaryRow aggregates ledger records sharing the grouped dimensions;
+// dimensions that were not grouped on are empty
+type UsageSummaryRow struct {
+	Day              string  `json:"day,omitempty"`
+	UserID           string  `json:"userId,omitempty"`
+	Task             string  `json:"task,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Calls            int     `json:"calls"`
+	FailedCalls      int     `json:"failedCalls"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+	AvgLatencyMs     int64   `json:"avgLatencyMs"`
+}
@@ -0,0 +1,155 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Limits on the advert attachments followed from one job page
+const (
+	maxAttachments         = 3
+	maxAttachmentBytes     = 10 << 20 // 10MB
+	attachmentTimeout      = 60 * time.Second
+	minAdvertImageSide     = 400      // px, when the page declares dimensions
+	minAdvertImageBytes    = 30 << 10 // smaller downloads are logos and icons
+	shortPageTextThreshold = 600      // characters; below this, undimensioned images are tried too
+)
+
+var (
+	attachmentLinkSelector = cascadia.MustCompile("a[href]")
+	advertImageSelector    = cascadia.MustCompile("img")
+)
+
+var attachmentDocumentExts = map[string]bool{".pdf": true, ".docx": true}
+var attachmentImageExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".bmp": true, ".tif": true, ".tiff": true}
+
+// extractAttachments downloads advert documents and large images found under
+// content and returns their extracted text, one labelled section per file.
+// Failures are logged and skipped so the page text is still usable.
+func (w *WebProcessor) extractAttachments(ctx context.Context, content *html.Node, pageURL string, pageTextLen int) string {
+	if w.Files == nil {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	attachments := findAttachments(content, base, pageTextLen < shortPageTextThreshold)
+	if len(attachments) == 0 {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, attachmentTimeout)
+	defer cancel()
+
+	fetcher := *w.Fetcher
+	fetcher.MaxBodyBytes = maxAttachmentBytes
+
+	var sections []string
+	for _, attachmentURL := range attachments {
+		text, err := w.extractAttachment(ctx, &fetcher, attachmentURL)
+		if err != nil {
+			log.Printf("Skipping attachment %s on %s: %v", attachmentURL, pageURL, err)
+			continue
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			sections = append(sections, fmt.Sprintf("--- Attached advert (%s) ---\n%s", path.Base(attachmentURL), text))
+		}
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// extractAttachment downloads one attachment and runs it through FileProcessor or OCR
+func (w *WebProcessor) extractAttachment(ctx context.Context, fetcher *Fetcher, attachmentURL string) (string, error) {
+	res, err := fetcher.Get(ctx, attachmentURL)
+	if err != nil {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(res.Body)
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		if len(res.Body) < minAdvertImageBytes {
+			return "", fmt.Errorf("image too small to be an advert (%d bytes)", len(res.Body))
+		}
+		ext := "." + strings.TrimPrefix(contentType, "image/")
+		if ext == ".jpeg" {
+			ext = ".jpg"
+		}
+		return w.Files.ProcessImageBuffer(ctx, res.Body, ext)
+	case contentType == "application/pdf":
+		text, _, err := w.Files.ProcessFileBuffer(ctx, res.Body, ".pdf")
+		return text, err
+	case contentType == "application/zip" && attachmentExt(attachmentURL) == ".docx":
+		text, _, err := w.Files.ProcessFileBuffer(ctx, res.Body, ".docx")
+		return text, err
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, contentType)
+	}
+}
+
+// findAttachments lists linked PDF/DOCX files and advert-sized images under
+// content, resolved against base. Images without declared dimensions are only
+// included when includeUnsized is set, i.e. when the page itself has little text.
+func findAttachments(content *html.Node, base *url.URL, includeUnsized bool) []string {
+	var found []string
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+		if key := u.String(); !seen[key] && len(found) < maxAttachments {
+			seen[key] = true
+			found = append(found, key)
+		}
+	}
+
+	for _, a := range cascadia.QueryAll(content, attachmentLinkSelector) {
+		href := getAttr(a, "href")
+		ext := attachmentExt(href)
+		switch {
+		case attachmentDocumentExts[ext]:
+			add(href)
+		case attachmentImageExts[ext] && includeUnsized:
+			// Linked full-size version of an advert thumbnail
+			add(href)
+		}
+	}
+
+	for _, img := range cascadia.QueryAll(content, advertImageSelector) {
+		src := getAttr(img, "data-src")
+		if src == "" {
+			src = getAttr(img, "src")
+		}
+		if src == "" || strings.HasPrefix(src, "data:") {
+			continue
+		}
+		width, _ := strconv.Atoi(strings.TrimSuffix(getAttr(img, "width"), "px"))
+		height, _ := strconv.Atoi(strings.TrimSuffix(getAttr(img, "height"), "px"))
+		if width >= minAdvertImageSide || height >= minAdvertImageSide || (width == 0 && height == 0 && includeUnsized) {
+			add(src)
+		}
+	}
+	return found
+}
+
+// attachmentExt returns the lower-case extension of a link's path, ignoring any query
+func attachmentExt(ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(path.Ext(u.Path))
+}
@@ -0,0 +1,194 @@
+package processors
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Class/id hints used when scoring content candidates
+var (
+	positiveContentHint = regexp.MustCompile(`(?i)job|vacanc|position|description|content|article|entry|main|post|body|text|detail`)
+	negativeContentHint = regexp.MustCompile(`(?i)nav|footer|sidebar|menu|comment|share|social|related|widget|banner|promo|breadcrumb|cookie|subscribe|newsletter|popup|modal|masthead`)
+)
+
+// boilerplateTags are removed before scoring; they never hold the posting text
+var boilerplateTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "iframe": true, "svg": true,
+}
+
+const (
+	minParagraphChars = 25 // shorter blocks are captions, buttons and labels
+	contentHintWeight = 25
+)
+
+// stripBoilerplate removes script, style, nav and similar nodes under n
+func stripBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && boilerplateTags[c.Data] {
+			n.RemoveChild(c)
+		} else if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else {
+			stripBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+// findBestContentNode scores block elements under root in the style of
+// Readability: each paragraph-like block scores by length and commas, and
+// passes that score to its parent and (halved) to its grandparent. Containers
+// are then adjusted by class/id hints, text density and link density, and the
+// highest-scoring one is returned. It returns nil if nothing scored.
+func (w *WebProcessor) findBestContentNode(root *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	paragraphs := make(map[*html.Node]int)
+
+	initialize := func(n *html.Node) {
+		if _, ok := scores[n]; ok {
+			return
+		}
+		scores[n] = tagBaseScore(n.Data) + classWeight(n)
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && isParagraphLike(n) {
+			text := strings.TrimSpace(collapseSpace(w.extractText(n)))
+			if len(text) >= minParagraphChars && n.Parent != nil && n.Parent.Type == html.ElementNode {
+				score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+				initialize(n.Parent)
+				scores[n.Parent] += score
+				paragraphs[n.Parent]++
+				if gp := n.Parent.Parent; gp != nil && gp.Type == html.ElementNode {
+					initialize(gp)
+					scores[gp] += score / 2
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	var best *html.Node
+	bestScore := 0.0
+	for n, score := range scores {
+		if n.Data == "body" || n.Data == "html" {
+			continue
+		}
+		// Several real paragraphs side by side is the strongest sign of body text
+		score += math.Min(float64(paragraphs[n]), 5)
+		score *= 1 - w.linkDensity(n)
+		score *= w.textDensityFactor(n)
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+// isParagraphLike reports whether n is a block that directly carries body text
+func isParagraphLike(n *html.Node) bool {
+	switch n.Data {
+	case "p", "pre", "td", "li", "blockquote", "dd":
+		return true
+	case "div", "section":
+		// Divs used as paragraphs have no block-level children
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				switch c.Data {
+				case "div", "p", "section", "article", "ul", "ol", "table", "pre", "blockquote", "h1", "h2", "h3", "h4", "h5", "h6":
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// tagBaseScore is the starting score of a container by tag
+func tagBaseScore(tag string) float64 {
+	switch tag {
+	case "article", "main":
+		return 10
+	case "div", "section":
+		return 5
+	case "pre", "td", "blockquote":
+		return 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li":
+		return -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th", "header", "footer", "aside":
+		return -5
+	}
+	return 0
+}
+
+// classWeight rewards or penalises a node by its class and id
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, attr := range []string{getAttr(n, "class"), getAttr(n, "id")} {
+		if attr == "" {
+			continue
+		}
+		if negativeContentHint.MatchString(attr) {
+			weight -= contentHintWeight
+		}
+		if positiveContentHint.MatchString(attr) {
+			weight += contentHintWeight
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of n's text that sits inside links
+func (w *WebProcessor) linkDensity(n *html.Node) float64 {
+	textLen := len(collapseSpace(w.extractText(n)))
+	if textLen == 0 {
+		return 1
+	}
+	linkLen := 0
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			linkLen += len(collapseSpace(w.extractText(c)))
+			return
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return math.Min(float64(linkLen)/float64(textLen), 1)
+}
+
+// textDensityFactor scales a score by characters per element, so wrappers
+// full of small widgets lose to compact blocks of prose. It ranges from 0.5 to 1.
+func (w *WebProcessor) textDensityFactor(n *html.Node) float64 {
+	elements := 0
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode {
+			elements++
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	density := float64(len(collapseSpace(w.extractText(n)))) / float64(elements)
+	return 0.5 + math.Min(density/100, 0.5)
+}
+
+// collapseSpace joins the whitespace-separated fields of s with single spaces
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,56 @@
+package processors
+
+import (
+	"errors"
+	"time"
+)
+
+// Extraction errors returned by FileProcessor. Callers should use errors.Is
+// to map them to client-facing responses; the wrapped message carries detail.
+var (
+	ErrUnsupportedFormat        = errors.New("unsupported file format")
+	ErrEncryptedDocument        = errors.New("document is password protected")
+	ErrTooManyPages             = errors.New("document has too many pages")
+	ErrDecompressedSizeExceeded = errors.New("document content exceeds size limit")
+	ErrExtractionTimeout        = errors.New("document extraction timed out")
+	ErrMalformedDocument        = errors.New("document is malformed or corrupted")
+)
+
+// ExtractorVersion identifies the behaviour of FileProcessor. Bump it whenever
+// extraction output changes so cached results from older versions are ignored.
+const ExtractorVersion = "2"
+
+// WebExtractorVersion identifies the behaviour of WebProcessor's page extraction.
+// Bump it whenever scraped job page text or fields change so cached pages are scraped again.
+const WebExtractorVersion = "2"
+
+// Default extraction limits applied by NewFileProcessor
+const (
+	defaultMaxPages             = 50
+	defaultMaxDecompressedBytes = 20 << 20 // 20MB
+	defaultMaxArchiveEntries    = 1000
+	defaultExtractionTimeout    = 30 * time.Second
+)
+
+// ExtractionLimits bounds the work done on a single uploaded file
+type ExtractionLimits struct {
+	// MaxPages is the maximum number of pages read from a PDF
+	MaxPages int
+	// MaxDecompressedBytes caps the extracted PDF text and the total
+	// uncompressed size of a DOCX archive
+	MaxDecompressedBytes int64
+	// MaxArchiveEntries caps the number of files inside a DOCX archive
+	MaxArchiveEntries int
+	// Timeout is the wall-clock budget for extracting one file
+	Timeout time.Duration
+}
+
+// DefaultExtractionLimits returns the limits used when none are configured
+func DefaultExtractionLimits() ExtractionLimits {
+	return ExtractionLimits{
+		MaxPages:             defaultMaxPages,
+		MaxDecompressedBytes: defaultMaxDecompressedBytes,
+		MaxArchiveEntries:    defaultMaxArchiveEntries,
+		Timeout:              defaultExtractionTimeout,
+	}
+}
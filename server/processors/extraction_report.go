@@ -0,0 +1,70 @@
+package processors
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"easy-apply/models"
+)
+
+// Quality thresholds used when scoring an extraction
+const (
+	goodTextLength      = 1500 // characters of a typical one-page resume
+	minLetterRatio      = 0.6  // share of non-space characters that should be letters
+	maxReplacementRatio = 0.01 // share of U+FFFD characters before text is considered garbled
+)
+
+// completeExtractionReport fills in the text-derived fields of report and
+// scores the extraction from 0 (unusable) to 1 (clean text)
+func completeExtractionReport(text string, report *models.ExtractionReport) {
+	report.Characters = utf8.RuneCountInString(text)
+	report.Language = DetectLanguage(text)
+
+	var letters, nonSpace, replacement int
+	for _, r := range text {
+		switch {
+		case r == utf8.RuneError:
+			replacement++
+			nonSpace++
+		case unicode.IsLetter(r):
+			letters++
+			nonSpace++
+		case !unicode.IsSpace(r):
+			nonSpace++
+		}
+	}
+
+	score := 1.0
+	if report.Characters < goodTextLength {
+		score *= float64(report.Characters) / goodTextLength
+	}
+	if report.Characters < minTextLength {
+		report.Warnings = append(report.Warnings, "Very little text could be extracted from this document.")
+	}
+
+	if nonSpace > 0 {
+		letterRatio := float64(letters) / float64(nonSpace)
+		if letterRatio < minLetterRatio {
+			score *= letterRatio / minLetterRatio
+			report.Warnings = append(report.Warnings, "The extracted text contains many symbols and may be garbled.")
+		}
+		if float64(replacement)/float64(nonSpace) > maxReplacementRatio {
+			score *= 0.5
+			report.Warnings = append(report.Warnings, "Some characters could not be decoded.")
+		}
+	}
+
+	if report.OCRUsed {
+		score *= 0.8
+		report.Warnings = append(report.Warnings, "The document appears to be scanned; text was recovered with OCR and may contain errors.")
+	}
+	if report.Language == LanguageUnknown && report.Characters >= minTextLength {
+		report.Warnings = append(report.Warnings, "The document language could not be identified.")
+	}
+	if strings.TrimSpace(text) == "" {
+		score = 0
+	}
+
+	report.QualityScore = float64(int(score*100+0.5)) / 100
+}
@@ -0,0 +1,509 @@
+package processors
+
+import (
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"easy-apply/models"
+
+	"golang.org/x/net/html"
+)
+
+// Claim categories checked by FabricationGuard
+const (
+	ClaimTypeOrganization = "ORGANIZATION"
+	ClaimTypeJobTitle     = "JOB_TITLE"
+	ClaimTypeDate         = "DATE"
+	ClaimTypeDegree       = "DEGREE"
+	ClaimTypeNumber       = "NUMBER"
+)
+
+// claimPattern finds one category of claim. If group is non-zero only that
+// capture group is the claim.
+type claimPattern struct {
+	claimType string
+	re        *regexp.Regexp
+	group     int
+}
+
+const (
+	capitalizedWord = `[A-Z][A-Za-z0-9&'.\-]*`
+	monthName       = `(?:January|February|March|April|May|June|July|August|September|October|November|December|Jan|Feb|Mar|Apr|Jun|Jul|Aug|Sept|Sep|Oct|Nov|Dec)\.?`
+)
+
+var claimPatterns = []claimPattern{
+	{
+		claimType: ClaimTypeOrganization,
+		re: regexp.MustCompile(`(?:` + capitalizedWord + `\s+){0,5}(?:Ltd|Limited|Inc|Corp|Corporation|LLC|Plc|PLC|Company|Bank|University|College|Institute|Foundation|Group|Ministry|Hospital|Trust|Association|Council|Agency|Authority|Society|Holdings|Consulting|Technologies|Solutions|Academy|Organisation|Organization|Commission)\b\.?` +
+			`(?:\s+of(?:\s+the)?(?:\s+` + capitalizedWord + `){1,4})?`),
+	},
+	{
+		claimType: ClaimTypeJobTitle,
+		re:        regexp.MustCompile(`(?:` + capitalizedWord + `\s+){0,4}(?:Manager|Engineer|Developer|Officer|Analyst|Director|Assistant|Intern|Consultant|Specialist|Coordinator|Accountant|Administrator|Designer|Teacher|Lecturer|Nurse|Architect|Scientist|Supervisor|Executive|Technician|Representative|Secretary|Clerk|Auditor|President|Advisor|Adviser|Programmer|Researcher)\b`),
+	},
+	{
+		claimType: ClaimTypeDegree,
+		re:        regexp.MustCompile(`\b(?:[Bb]achelor|[Mm]aster|[Dd]octor|[Aa]ssociate)(?:'s)?\s+(?:[Dd]egree\s+)?(?:of|in)\s+(?:(?:of|and|in|&)\s+)?` + capitalizedWord + `(?:\s+(?:(?:of|and|in|&)\s+)?` + capitalizedWord + `){0,4}`),
+	},
+	{
+		claimType: ClaimTypeDegree,
+		re:        regexp.MustCompile(`\b(?:B\.?\s?Sc|M\.?\s?Sc|B\.?\s?Eng|M\.?\s?Eng|B\.?\s?Com|M\.?\s?Com|MBA|Ph\.?\s?D|LLB|LLM|B\.?\s?Ed|M\.?\s?Ed)\b\.?(?:\s+(?:in|of)\s+` + capitalizedWord + `(?:\s+(?:and\s+|&\s+)?` + capitalizedWord + `){0,3})?`),
+	},
+	{
+		claimType: ClaimTypeDegree,
+		re:        regexp.MustCompile(`(?:\b(?:Diploma|Certificate)\s+in\s+` + capitalizedWord + `(?:\s+(?:and\s+|&\s+)?` + capitalizedWord + `){0,3})|(?:(?:` + capitalizedWord + `\s+){0,3}Certified(?:\s+` + capitalizedWord + `){1,5})|\b(?:PMP|CPA|ACCA|CFA|CISSP|CISA|CCNA|CCNP|CIMA|PRINCE2|ITIL)\b`),
+	},
+	{
+		claimType: ClaimTypeDate,
+		re:        regexp.MustCompile(`\b(?:` + monthName + `\s+)?(?:19[5-9]\d|20[0-4]\d)\b`),
+	},
+	{
+		claimType: ClaimTypeNumber,
+		re:        regexp.MustCompile(`(?i)(?:[$£€]|\b(?:USD|MWK|ZAR|GBP|EUR|K)\s?)?\b\d[\d,]*(?:\.\d+)?(?:\s?(?:%|\+|x\b|k\b|m\b|bn\b|million\b|billion\b|thousand\b))?`),
+	},
+}
+
+var (
+	yearPattern      = regexp.MustCompile(`\b(?:19[5-9]\d|20[0-4]\d)\b`)
+	monthYearPattern = regexp.MustCompile(`\b(` + monthName + `)\s+((?:19|20)\d{2})\b`)
+	numberPattern    = regexp.MustCompile(`(?i)\d[\d,]*(?:\.\d+)?(?:\s?(k|m|bn|million|billion|thousand)\b)?`)
+)
+
+// claimStopwords lead into a capitalised phrase without being part of it
+var claimStopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "at": true, "as": true, "for": true, "with": true, "in": true,
+	"of": true, "and": true, "to": true, "from": true, "by": true, "on": true, "joined": true,
+	"former": true, "current": true, "worked": true, "served": true, "our": true, "my": true,
+}
+
+// organizationSuffixes are left out when comparing organisation names, so
+// "Acme Ltd" is supported by "Acme Limited"
+var organizationSuffixes = map[string]bool{
+	"ltd": true, "limited": true, "inc": true, "corp": true, "corporation": true, "llc": true,
+	"plc": true, "company": true, "co": true,
+}
+
+// seniorityWords change what a job title claims, so they must be in the source
+var seniorityWords = map[string]bool{
+	"senior": true, "junior": true, "lead": true, "principal": true, "chief": true, "head": true,
+	"staff": true, "deputy": true, "vice": true, "assistant": true, "associate": true, "executive": true,
+}
+
+// degreeLevels maps degree words and abbreviations to the level they name
+var degreeLevels = map[string]string{
+	"bachelor": "bachelor", "bachelors": "bachelor", "bsc": "bachelor", "ba": "bachelor", "bs": "bachelor",
+	"beng": "bachelor", "bcom": "bachelor", "bed": "bachelor", "llb": "bachelor",
+	"master": "master", "masters": "master", "msc": "master", "ma": "master", "ms": "master",
+	"meng": "master", "mcom": "master", "med": "master", "mba": "master", "llm": "master",
+	"doctor": "doctor", "doctorate": "doctor", "phd": "doctor",
+	"associate": "associate",
+}
+
+// FabricationGuard checks generated resumes for employers, titles, dates,
+// degrees and figures that the original resume does not contain. It is a
+// heuristic: it flags claims for review and only removes them in strict mode.
+type FabricationGuard struct {
+	Enabled bool
+	Strict  bool
+}
+
+// NewFabricationGuard creates a guard
+func NewFabricationGuard(enabled, strict bool) *FabricationGuard {
+	return &FabricationGuard{Enabled: enabled, Strict: strict}
+}
+
+// NewFabricationGuardFromEnv configures a guard from FABRICATION_GUARD_ENABLED
+// (default true) and FABRICATION_GUARD_STRICT (default false)
+func NewFabricationGuardFromEnv() *FabricationGuard {
+	return NewFabricationGuard(
+		!strings.EqualFold(os.Getenv("FABRICATION_GUARD_ENABLED"), "false"),
+		strings.EqualFold(os.Getenv("FABRICATION_GUARD_STRICT"), "true"),
+	)
+}
+
+// sourceFacts indexes the original resume for claim lookups
+type sourceFacts struct {
+	text       string // normalized, padded with spaces
+	words      map[string]bool
+	years      map[string]bool
+	monthYears map[string]bool // "jan 2020"
+	monthsIn   map[string]bool // years that appear with a month
+	numbers    map[string]bool
+	levels     map[string]bool
+}
+
+func newSourceFacts(source string) *sourceFacts {
+	f := &sourceFacts{
+		text:       " " + normalizeClaim(source) + " ",
+		words:      make(map[string]bool),
+		years:      make(map[string]bool),
+		monthYears: make(map[string]bool),
+		monthsIn:   make(map[string]bool),
+		numbers:    make(map[string]bool),
+		levels:     make(map[string]bool),
+	}
+	for _, word := range strings.Fields(f.text) {
+		f.words[word] = true
+		if level, ok := degreeLevels[word]; ok {
+			f.levels[level] = true
+		}
+	}
+	for _, year := range yearPattern.FindAllString(source, -1) {
+		f.years[year] = true
+	}
+	for _, match := range monthYearPattern.FindAllStringSubmatch(source, -1) {
+		f.monthYears[monthKey(match[1])+" "+match[2]] = true
+		f.monthsIn[match[2]] = true
+	}
+	for _, number := range numberPattern.FindAllString(source, -1) {
+		for _, key := range numberKeys(number) {
+			f.numbers[key] = true
+		}
+	}
+	return f
+}
+
+// Check returns the claims in generated (resume HTML or text) that source
+// does not support. Claims matching one of allowed, such as the target job
+// title and company, are not flagged.
+func (g *FabricationGuard) Check(source, generated string, allowed ...string) []models.FabricationFlag {
+	if g == nil || !g.Enabled || strings.TrimSpace(generated) == "" {
+		return nil
+	}
+	facts := newSourceFacts(source)
+
+	var allowedNorm []string
+	for _, a := range allowed {
+		if n := normalizeClaim(a); n != "" {
+			allowedNorm = append(allowedNorm, " "+n+" ")
+		}
+	}
+
+	var flags []models.FabricationFlag
+	seen := make(map[string]bool)
+	for _, segment := range textSegments(generated) {
+		// Text already claimed by an earlier pattern (e.g. the year of a month-year date,
+		// or the figures in a degree name) is not checked again
+		claimed := make([]bool, len(segment))
+		for _, pattern := range claimPatterns {
+			for _, match := range pattern.re.FindAllStringSubmatchIndex(segment, -1) {
+				start, end := match[2*pattern.group], match[2*pattern.group+1]
+				if start < 0 || overlapsClaimed(claimed, start, end) {
+					continue
+				}
+				claim := trimClaim(segment[start:end])
+				if claim == "" {
+					continue
+				}
+				for i := start; i < end; i++ {
+					claimed[i] = true
+				}
+
+				key := pattern.claimType + ":" + normalizeClaim(claim)
+				if seen[key] || isAllowedClaim(claim, allowedNorm) || facts.supports(pattern.claimType, claim) {
+					continue
+				}
+				seen[key] = true
+				flags = append(flags, models.FabricationFlag{
+					Type:    pattern.claimType,
+					Claim:   claim,
+					Context: claimContext(segment, start, end),
+				})
+			}
+		}
+	}
+	return flags
+}
+
+// supports reports whether the original resume backs a claim
+func (f *sourceFacts) supports(claimType, claim string) bool {
+	normalized := normalizeClaim(claim)
+	if normalized == "" || strings.Contains(f.text, " "+normalized+" ") {
+		return true
+	}
+
+	switch claimType {
+	case ClaimTypeOrganization:
+		return f.hasWords(normalized, organizationSuffixes)
+	case ClaimTypeJobTitle:
+		// Leading descriptive words ("Experienced Software Engineer") are not part of
+		// the title, but an added seniority is
+		words := strings.Fields(normalized)
+		for len(words) > 1 && !f.words[words[0]] && !seniorityWords[words[0]] {
+			words = words[1:]
+		}
+		return f.hasWords(strings.Join(words, " "), nil)
+	case ClaimTypeDegree:
+		words := strings.Fields(normalized)
+		level := ""
+		var field []string
+		for _, word := range words {
+			if l, ok := degreeLevels[word]; ok && level == "" {
+				level = l
+				continue
+			}
+			if word != "degree" {
+				field = append(field, word)
+			}
+		}
+		if level != "" && !f.levels[level] {
+			return false
+		}
+		return f.hasWords(strings.Join(field, " "), nil)
+	case ClaimTypeDate:
+		if match := monthYearPattern.FindStringSubmatch(claim); match != nil {
+			year := match[2]
+			if !f.years[year] {
+				return false
+			}
+			// A resume that only gives years cannot contradict a month
+			return !f.monthsIn[year] || f.monthYears[monthKey(match[1])+" "+year]
+		}
+		return f.years[strings.TrimSpace(claim)]
+	case ClaimTypeNumber:
+		for _, key := range numberKeys(claim) {
+			if f.numbers[key] {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// hasWords reports whether every significant word of a normalized phrase is in the source
+func (f *sourceFacts) hasWords(normalized string, ignore map[string]bool) bool {
+	for _, word := range strings.Fields(normalized) {
+		if claimStopwords[word] || ignore[word] {
+			continue
+		}
+		if !f.words[word] {
+			return false
+		}
+	}
+	return true
+}
+
+func isAllowedClaim(claim string, allowed []string) bool {
+	normalized := " " + normalizeClaim(claim) + " "
+	for _, a := range allowed {
+		if strings.Contains(a, normalized) || strings.Contains(normalized, a) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapsClaimed(claimed []bool, start, end int) bool {
+	for i := start; i < end; i++ {
+		if claimed[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// trimClaim drops leading connectives picked up by the capitalised-word patterns
+func trimClaim(claim string) string {
+	words := strings.Fields(claim)
+	for len(words) > 0 && claimStopwords[strings.ToLower(words[0])] {
+		words = words[1:]
+	}
+	return strings.TrimRight(strings.Join(words, " "), ",;:")
+}
+
+// normalizeClaim lowercases text and reduces it to words separated by single spaces
+func normalizeClaim(text string) string {
+	text = strings.ReplaceAll(strings.ToLower(text), "'s", "")
+	var sb strings.Builder
+	space := true
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+			space = false
+		case r == '.' || r == ',':
+			// "B.Sc" is "bsc" and "1,200" is "1200"
+		default:
+			if !space {
+				sb.WriteByte(' ')
+				space = true
+			}
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func monthKey(month string) string {
+	return strings.ToLower(month)[:3]
+}
+
+// numberKeys returns the forms a figure may take in the source: its digits
+// and, when it has a scale such as "k" or "million", its full value
+func numberKeys(number string) []string {
+	match := numberPattern.FindStringSubmatch(number)
+	if match == nil {
+		return nil
+	}
+	digits := strings.ReplaceAll(match[0], ",", "")
+	if i := strings.IndexFunc(digits, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' }); i >= 0 {
+		digits = digits[:i]
+	}
+	digits = strings.TrimSuffix(strings.TrimRight(digits, "."), ".0")
+	keys := []string{digits}
+
+	scale := map[string]float64{"k": 1e3, "thousand": 1e3, "m": 1e6, "million": 1e6, "bn": 1e9, "billion": 1e9}[strings.ToLower(match[1])]
+	if value, err := strconv.ParseFloat(digits, 64); err == nil && scale > 0 {
+		keys = append(keys, strconv.FormatFloat(math.Round(value*scale), 'f', -1, 64))
+	}
+	return keys
+}
+
+// claimContext returns the text around a claim, for display
+func claimContext(segment string, start, end int) string {
+	const radius = 60
+	from, to := start-radius, end+radius
+	if from < 0 {
+		from = 0
+	}
+	if to > len(segment) {
+		to = len(segment)
+	}
+	// Do not cut a multi-byte character in half
+	for from > 0 && !isRuneStart(segment[from]) {
+		from--
+	}
+	for to < len(segment) && !isRuneStart(segment[to]) {
+		to++
+	}
+	context := strings.TrimSpace(segment[from:to])
+	if from > 0 {
+		context = "…" + context
+	}
+	if to < len(segment) {
+		context += "…"
+	}
+	return context
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// textSegments returns the visible text of an HTML document, one segment per
+// text node. Plain text comes back as a single segment.
+func textSegments(document string) []string {
+	var segments []string
+	tokenizer := html.NewTokenizer(strings.NewReader(document))
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return segments
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "script" || string(name) == "style" {
+				skip++
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); (string(name) == "script" || string(name) == "style") && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			if text := strings.Join(strings.Fields(string(tokenizer.Text())), " "); text != "" {
+				segments = append(segments, text)
+			}
+		}
+	}
+}
+
+// listItemPattern matches an <li> element whose content has no nested list items
+var listItemPattern = regexp.MustCompile(`(?is)<li\b[^>]*>(?:[^<]|<(?:[^l/]|/[^l]|l[^i]|/l[^i]))*?</li>`)
+
+// minStrippableSentenceWords keeps short fragments such as dates from being
+// stripped as sentences
+const minStrippableSentenceWords = 6
+
+// StripUnsupportedClaims removes flagged claims from resume HTML: the list
+// item that holds a claim, or otherwise the sentence. Claims in headings and
+// short fragments are left in place. The returned flags record what was stripped.
+func StripUnsupportedClaims(document string, flags []models.FabricationFlag) (string, []models.FabricationFlag) {
+	stripped := make([]models.FabricationFlag, len(flags))
+	copy(stripped, flags)
+
+	type cut struct{ start, end int }
+	var cuts []cut
+	for i, flag := range stripped {
+		escaped := html.EscapeString(flag.Claim)
+		for _, claim := range []string{flag.Claim, escaped} {
+			pos := strings.Index(document, claim)
+			if pos < 0 {
+				continue
+			}
+			if item := enclosingListItem(document, pos); item != nil {
+				cuts = append(cuts, cut{item[0], item[1]})
+				stripped[i].Stripped = true
+				break
+			}
+			if start, end, ok := enclosingSentence(document, pos, pos+len(claim)); ok {
+				cuts = append(cuts, cut{start, end})
+				stripped[i].Stripped = true
+				break
+			}
+		}
+	}
+	if len(cuts) == 0 {
+		return document, stripped
+	}
+
+	// Remove from the end so earlier offsets stay valid, skipping overlaps
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].start > cuts[j].start })
+	last := len(document) + 1
+	for _, c := range cuts {
+		if c.end > last {
+			continue
+		}
+		document = document[:c.start] + document[c.end:]
+		last = c.start
+	}
+	return document, stripped
+}
+
+func enclosingListItem(document string, pos int) []int {
+	for _, loc := range listItemPattern.FindAllStringIndex(document, -1) {
+		if loc[0] <= pos && pos < loc[1] {
+			return loc
+		}
+		if loc[0] > pos {
+			break
+		}
+	}
+	return nil
+}
+
+// enclosingSentence finds the sentence around [start, end) within its text node
+func enclosingSentence(document string, start, end int) (int, int, bool) {
+	from := strings.LastIndexAny(document[:start], ".!?>") + 1
+	to := strings.IndexAny(document[end:], ".!?<")
+	if to < 0 {
+		return 0, 0, false
+	}
+	to += end
+	startsAtTag := from == 0 || document[from-1] == '>'
+	endsAtTag := document[to] == '<'
+	if !endsAtTag {
+		to++ // keep the markup, drop the punctuation
+	}
+	// Text bounded by markup on both sides is a heading or a line, not a sentence
+	if (startsAtTag && endsAtTag) || len(strings.Fields(document[from:to])) < minStrippableSentenceWords {
+		return 0, 0, false
+	}
+	return from, to, true
+}
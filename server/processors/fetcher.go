@@ -0,0 +1,230 @@
+package processors
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"easy-apply/retry"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Fetch errors returned by Fetcher. Use errors.Is to tell them apart.
+var (
+	ErrResponseTooLarge = errors.New("response body exceeds size limit")
+	ErrRobotsDisallowed = errors.New("fetching is disallowed by robots.txt")
+	ErrUnexpectedStatus = errors.New("unexpected HTTP status")
+)
+
+// Default fetcher settings applied by NewFetcher
+const (
+	defaultFetchUserAgent      = "EasyApplyBot/1.0 (+https://easyapply.mw)"
+	defaultFetchTimeout        = 20 * time.Second
+	defaultFetchMaxBodyBytes   = 5 << 20 // 5MB
+	defaultFetchMaxRetries     = 3
+	defaultFetchInitialBackoff = 500 * time.Millisecond
+	defaultFetchMaxBackoff     = 8 * time.Second
+)
+
+// Fetcher retrieves web pages for extraction and link checks. It bounds every
+// request in time and size, retries transient failures and decodes bodies to UTF-8.
+type Fetcher struct {
+	Client         *http.Client
+	UserAgent      string
+	MaxBodyBytes   int64
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RespectRobots makes the fetcher consult robots.txt before each request
+	RespectRobots bool
+
+	robots *robotsCache
+}
+
+// FetchResult is a fetched response. Body is UTF-8 for text content.
+type FetchResult struct {
+	URL         string // final URL after redirects
+	StatusCode  int
+	Status      string
+	Header      http.Header
+	ContentType string
+	Body        []byte
+}
+
+// NewFetcher creates a fetcher with the default limits that only connects to
+// public addresses. robots.txt compliance is enabled by setting FETCH_RESPECT_ROBOTS=true.
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Client: &http.Client{
+			Timeout:       defaultFetchTimeout,
+			Transport:     newSafeTransport(),
+			CheckRedirect: checkRedirectTarget,
+		},
+		UserAgent:      defaultFetchUserAgent,
+		MaxBodyBytes:   defaultFetchMaxBodyBytes,
+		MaxRetries:     defaultFetchMaxRetries,
+		InitialBackoff: defaultFetchInitialBackoff,
+		MaxBackoff:     defaultFetchMaxBackoff,
+		RespectRobots:  strings.EqualFold(os.Getenv("FETCH_RESPECT_ROBOTS"), "true"),
+		robots:         newRobotsCache(),
+	}
+}
+
+// Get fetches a URL and returns its body. Non-2xx responses are returned as
+// ErrUnexpectedStatus alongside the result so callers can inspect the status.
+func (f *Fetcher) Get(ctx context.Context, rawURL string) (*FetchResult, error) {
+	return f.GetConditional(ctx, rawURL, "", "")
+}
+
+// GetConditional is Get with If-None-Match/If-Modified-Since validators. A
+// 304 Not Modified response is returned without a body and without an error.
+func (f *Fetcher) GetConditional(ctx context.Context, rawURL, etag, lastModified string) (*FetchResult, error) {
+	if err := ValidatePublicURL(ctx, rawURL); err != nil {
+		return nil, err
+	}
+	if err := f.checkRobots(ctx, rawURL); err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := f.do(ctx, http.MethodGet, rawURL, f.Client, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := newFetchResult(resp)
+	if resp.StatusCode == http.StatusNotModified && len(header) > 0 {
+		return result, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+
+	body, err := f.readBody(resp)
+	if err != nil {
+		return result, err
+	}
+	result.Body = body
+	return result, nil
+}
+
+// do sends a request, retrying network errors, 5xx and 429 responses under
+// the fetcher's retry policy. Retry-After is honoured up to MaxBackoff; the
+// response to the last attempt is returned whatever its status. header is
+// added to every attempt.
+func (f *Fetcher) do(ctx context.Context, method, rawURL string, client *http.Client, header http.Header) (*http.Response, error) {
+	attempts := f.MaxRetries + 1
+	policy := retry.Policy{
+		MaxAttempts:    attempts,
+		InitialBackoff: f.InitialBackoff,
+		MaxBackoff:     f.MaxBackoff,
+		MaxRetryAfter:  f.MaxBackoff,
+	}
+	return retry.Do(ctx, policy, fmt.Sprintf("Fetch %s %s", method, rawURL), func(attempt int) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return nil, retry.NewError(retry.ClassInvalidRequest, fmt.Errorf("invalid request for %s: %w", rawURL, err))
+		}
+		req.Header.Set("User-Agent", f.UserAgent)
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		req.Header.Set("Accept-Encoding", "gzip")
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if errors.Is(err, ErrUnsafeURL) {
+				return nil, retry.NewError(retry.ClassInvalidRequest, err)
+			}
+			return nil, err
+		}
+		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) && attempt < attempts {
+			resp.Body.Close()
+			statusErr := retry.StatusError(resp, fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status))
+			// A long Retry-After is capped rather than ending the retries
+			if statusErr.RetryAfter > f.MaxBackoff {
+				statusErr.RetryAfter = f.MaxBackoff
+			}
+			return nil, statusErr
+		}
+		return resp, nil
+	})
+}
+
+// readBody reads at most MaxBodyBytes of decompressed content and converts
+// text bodies to UTF-8 using the declared or sniffed charset
+func (f *Fetcher) readBody(resp *http.Response) ([]byte, error) {
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(body, f.MaxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if int64(len(raw)) > f.MaxBodyBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, f.MaxBodyBytes)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !isTextContent(contentType) {
+		return raw, nil
+	}
+	decoded, err := charset.NewReader(bytes.NewReader(raw), contentType)
+	if err != nil {
+		log.Printf("Unknown charset in %q, using raw body: %v", contentType, err)
+		return raw, nil
+	}
+	utf8Body, err := io.ReadAll(decoded)
+	if err != nil {
+		return raw, nil
+	}
+	return utf8Body, nil
+}
+
+// isTextContent reports whether a Content-Type holds text that should be charset-decoded
+func isTextContent(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "xml") || strings.HasSuffix(mediaType, "json")
+}
+
+func newFetchResult(resp *http.Response) *FetchResult {
+	return &FetchResult{
+		URL:         resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+		Status:      resp.Status,
+		Header:      resp.Header,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+}
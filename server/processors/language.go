@@ -0,0 +1,131 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrUnsupportedLanguage is returned for an output language documents cannot be generated in
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// Language codes returned by DetectLanguage (ISO 639-1 where one exists)
+const (
+	LanguageEnglish    = "en"
+	LanguageFrench     = "fr"
+	LanguagePortuguese = "pt"
+	LanguageChichewa   = "ny"
+	LanguageSwahili    = "sw"
+	LanguageUnknown    = "und"
+)
+
+// languageNames are the languages documents can be generated in, by code
+var languageNames = map[string]string{
+	LanguageEnglish:    "English",
+	LanguageFrench:     "French",
+	LanguagePortuguese: "Portuguese",
+	LanguageChichewa:   "Chichewa",
+	LanguageSwahili:    "Swahili",
+}
+
+// languageAliases are other names accepted for an output language
+var languageAliases = map[string]string{
+	"français": LanguageFrench, "português": LanguagePortuguese, "nyanja": LanguageChichewa,
+	"chinyanja": LanguageChichewa, "kiswahili": LanguageSwahili,
+}
+
+// languageStopwords lists frequent function words for each supported language.
+// Words that are also common in another supported language (such as "wa" and
+// "ya" in Chichewa and Swahili, or "as" and "do" in English and Portuguese)
+// are left out so they cannot tip the guess either way.
+var languageStopwords = map[string][]string{
+	LanguageEnglish:    {"the", "and", "of", "to", "in", "for", "with", "is", "are", "on", "will", "be", "experience", "responsibilities"},
+	LanguageFrench:     {"le", "la", "les", "des", "et", "du", "pour", "dans", "avec", "est", "une", "sur", "expérience", "poste"},
+	LanguagePortuguese: {"os", "da", "dos", "das", "para", "com", "em", "uma", "não", "ou", "são", "também", "experiência", "vaga"},
+	LanguageChichewa:   {"ndi", "kuti", "kapena", "komanso", "ntchito", "pa", "ku", "mu", "ndipo", "monga", "zomwe", "amene", "chifukwa", "anthu"},
+	LanguageSwahili:    {"katika", "ni", "kazi", "pamoja", "hii", "kama", "uzoefu", "kwamba", "lazima", "yake", "zaidi", "hivyo", "wafanyakazi", "ujuzi"},
+}
+
+// minLanguageHits is the minimum number of stopword matches needed before a
+// language is reported rather than LanguageUnknown
+const minLanguageHits = 5
+
+// DetectLanguage guesses the dominant language of text by stopword frequency.
+// A tie goes to the language with more distinct stopwords in the text; text
+// that is still tied is reported as LanguageUnknown.
+func DetectLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) == 0 {
+		return LanguageUnknown
+	}
+
+	counts := make(map[string]int, len(words))
+	for _, word := range words {
+		counts[word]++
+	}
+
+	best, bestScore, bestDistinct, tied := LanguageUnknown, 0, 0, false
+	for lang, stopwords := range languageStopwords {
+		score, distinct := 0, 0
+		for _, sw := range stopwords {
+			if counts[sw] > 0 {
+				score += counts[sw]
+				distinct++
+			}
+		}
+		switch {
+		case score > bestScore || (score == bestScore && distinct > bestDistinct):
+			best, bestScore, bestDistinct, tied = lang, score, distinct, false
+		case score == bestScore && distinct == bestDistinct:
+			tied = true
+		}
+	}
+
+	if bestScore < minLanguageHits || tied {
+		return LanguageUnknown
+	}
+	return best
+}
+
+// LanguageName returns the English name of a language code, for prompts
+func LanguageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return languageNames[LanguageEnglish]
+}
+
+// NormalizeLanguage maps a language code or name (e.g. "fr", "French") to its code
+func NormalizeLanguage(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if _, ok := languageNames[value]; ok {
+		return value, nil
+	}
+	if code, ok := languageAliases[value]; ok {
+		return code, nil
+	}
+	for code, name := range languageNames {
+		if strings.EqualFold(name, value) {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedLanguage, value)
+}
+
+// OutputLanguage picks the language to generate documents in: the requested
+// one if set, otherwise the job posting's, then the resume's, then English.
+// The detected languages are codes from DetectLanguage.
+func OutputLanguage(requested, postingLanguage, resumeLanguage string) (string, error) {
+	if strings.TrimSpace(requested) != "" {
+		return NormalizeLanguage(requested)
+	}
+	for _, detected := range []string{postingLanguage, resumeLanguage} {
+		if _, ok := languageNames[detected]; ok {
+			return detected, nil
+		}
+	}
+	return LanguageEnglish, nil
+}
@@ -0,0 +1,355 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// LinkVerdict summarises whether a job link is worth tailoring against
+type LinkVerdict string
+
+const (
+	LinkLive        LinkVerdict = "live"        // the posting loads and looks open
+	LinkExpired     LinkVerdict = "expired"     // removed, closed or past its deadline
+	LinkMoved       LinkVerdict = "moved"       // redirects somewhere other than the posting
+	LinkBlocked     LinkVerdict = "blocked"     // the site or our URL policy refuses access
+	LinkUnreachable LinkVerdict = "unreachable" // network failure, server error or redirect loop
+)
+
+const (
+	maxLinkRedirects   = 5
+	maxLinkCheckBytes  = 1 << 20 // 1MB of page is plenty to spot an expired notice
+	linkCheckTextChars = 4000
+)
+
+// RedirectHop is one response in a redirect chain
+type RedirectHop struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// LinkHealth is the outcome of checking a job link
+type LinkHealth struct {
+	Verdict     LinkVerdict   `json:"verdict"`
+	Status      int           `json:"status,omitempty"`
+	FinalURL    string        `json:"finalUrl,omitempty"`
+	Redirects   []RedirectHop `json:"redirects,omitempty"`
+	ContentType string        `json:"contentType,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// defaultExpiredPatterns match notices that a posting is closed or gone even
+// though the page returns 200
+var defaultExpiredPatterns = []string{
+	`(?i)\bposition (has been |is )?filled\b`,
+	`(?i)\bno longer (available|accepting applications|open)\b`,
+	`(?i)\b(job|vacancy|position|posting|advert) (has )?(expired|closed|been removed)\b`,
+	`(?i)\bapplications? (are |is )?(now )?closed\b`,
+	`(?i)\b(job|vacancy|page|listing) (was )?not found\b`,
+	`(?i)\bdeadline (has )?passed\b`,
+	`(?i)^\s*404\b`,
+}
+
+// ExpiredPagePatterns holds soft-404 patterns per domain substring; the "*"
+// entry applies to every site. Extra patterns are loaded from the JSON file
+// named by LINK_EXPIRED_PATTERNS_FILE, shaped like {"*": [...], "example.com": [...]}.
+type ExpiredPagePatterns struct {
+	patterns map[string][]*regexp.Regexp
+}
+
+var (
+	expiredPatternsOnce sync.Once
+	expiredPatterns     *ExpiredPagePatterns
+)
+
+// LoadExpiredPagePatterns returns the shared soft-404 patterns, loading the
+// configured file on first use. Invalid entries are logged and skipped.
+func LoadExpiredPagePatterns() *ExpiredPagePatterns {
+	expiredPatternsOnce.Do(func() {
+		config := map[string][]string{"*": defaultExpiredPatterns}
+		if path := os.Getenv("LINK_EXPIRED_PATTERNS_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err == nil {
+				var extra map[string][]string
+				if err = json.Unmarshal(data, &extra); err == nil {
+					for domain, list := range extra {
+						config[strings.ToLower(domain)] = append(config[strings.ToLower(domain)], list...)
+					}
+				}
+			}
+			if err != nil {
+				log.Printf("Could not load expired page patterns from %s: %v", path, err)
+			}
+		}
+		expiredPatterns = NewExpiredPagePatterns(config)
+	})
+	return expiredPatterns
+}
+
+// NewExpiredPagePatterns compiles patterns keyed by domain substring
+func NewExpiredPagePatterns(config map[string][]string) *ExpiredPagePatterns {
+	p := &ExpiredPagePatterns{patterns: make(map[string][]*regexp.Regexp)}
+	for domain, list := range config {
+		for _, pattern := range list {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				log.Printf("Invalid expired page pattern %q for %s: %v", pattern, domain, err)
+				continue
+			}
+			p.patterns[domain] = append(p.patterns[domain], re)
+		}
+	}
+	return p
+}
+
+// Match returns the first pattern for host that matches text
+func (p *ExpiredPagePatterns) Match(host, text string) (string, bool) {
+	host = strings.ToLower(host)
+	for domain, list := range p.patterns {
+		if domain != "*" && !strings.Contains(host, domain) {
+			continue
+		}
+		for _, re := range list {
+			if re.MatchString(text) {
+				return re.String(), true
+			}
+		}
+	}
+	return "", false
+}
+
+// CheckLink follows up to maxLinkRedirects redirects from rawURL, validating
+// every hop, and classifies the final page. Expired postings are detected from
+// status codes, soft-404 patterns and past validThrough dates in schema.org data.
+// Each hop is probed with HEAD first; only HTML pages, which need their body
+// checked for soft-404s, and servers that reject HEAD are fetched with GET.
+func (w *WebProcessor) CheckLink(ctx context.Context, rawURL string) LinkHealth {
+	health := LinkHealth{}
+	if err := ValidatePublicURL(ctx, rawURL); err != nil {
+		health.Error = err.Error()
+		if errors.Is(err, ErrUnsafeURL) {
+			health.Verdict = LinkBlocked
+			health.Reason = "The link points to a private or unsupported address"
+		} else {
+			health.Verdict = LinkUnreachable
+			health.Reason = "The link's host could not be resolved"
+		}
+		return health
+	}
+	if err := w.Fetcher.checkRobots(ctx, rawURL); err != nil {
+		health.Verdict, health.Reason, health.Error = LinkBlocked, "The site does not allow automated access to this page", err.Error()
+		return health
+	}
+
+	fetcher := *w.Fetcher
+	fetcher.MaxBodyBytes = maxLinkCheckBytes
+	client := *w.Fetcher.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	current := rawURL
+	for {
+		resp, err := fetcher.probeHop(ctx, current, &client)
+		if err != nil {
+			health.Error = err.Error()
+			health.FinalURL = current
+			if errors.Is(err, ErrUnsafeURL) {
+				health.Verdict, health.Reason = LinkBlocked, "The link redirects to a private or unsupported address"
+			} else {
+				health.Verdict, health.Reason = LinkUnreachable, "The site could not be reached"
+			}
+			return health
+		}
+
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get("Location") != "" {
+			resp.Body.Close()
+			health.Redirects = append(health.Redirects, RedirectHop{URL: current, Status: resp.StatusCode})
+			next, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+			if err == nil {
+				err = CheckURLTarget(next)
+			}
+			if err != nil {
+				health.Verdict, health.Reason, health.Error = LinkBlocked, "The link redirects to a private or unsupported address", err.Error()
+				return health
+			}
+			if len(health.Redirects) > maxLinkRedirects {
+				health.Verdict, health.Reason = LinkUnreachable, fmt.Sprintf("The link redirects more than %d times", maxLinkRedirects)
+				health.FinalURL = next.String()
+				return health
+			}
+			current = next.String()
+			continue
+		}
+
+		defer resp.Body.Close()
+		health.Status = resp.StatusCode
+		health.FinalURL = current
+		health.ContentType = resp.Header.Get("Content-Type")
+		w.classifyResponse(&fetcher, resp, rawURL, &health)
+		return health
+	}
+}
+
+// probeHop requests one hop of a link check. It sends HEAD and keeps the
+// response for redirects and non-HTML content, which need no body; otherwise,
+// or when the server rejects or fails HEAD as many job boards do, it sends GET.
+func (f *Fetcher) probeHop(ctx context.Context, rawURL string, client *http.Client) (*http.Response, error) {
+	resp, err := f.do(ctx, http.MethodHead, rawURL, client, nil)
+	if err == nil && resp.StatusCode < 400 {
+		contentType := resp.Header.Get("Content-Type")
+		redirect := resp.StatusCode >= 300 && resp.Header.Get("Location") != ""
+		if redirect || (contentType != "" && !strings.Contains(contentType, "html")) {
+			return resp, nil
+		}
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if errors.Is(err, ErrUnsafeURL) {
+		return nil, err
+	}
+	return f.do(ctx, http.MethodGet, rawURL, client, nil)
+}
+
+// classifyResponse sets the verdict for the final, non-redirect response
+func (w *WebProcessor) classifyResponse(fetcher *Fetcher, resp *http.Response, requestedURL string, health *LinkHealth) {
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		health.Verdict, health.Reason = LinkExpired, fmt.Sprintf("The posting was removed (HTTP %d)", resp.StatusCode)
+		return
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden ||
+		resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusUnavailableForLegalReasons:
+		health.Verdict, health.Reason = LinkBlocked, fmt.Sprintf("The site refused access (HTTP %d)", resp.StatusCode)
+		return
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		health.Verdict, health.Reason = LinkUnreachable, fmt.Sprintf("The site returned HTTP %d", resp.StatusCode)
+		return
+	}
+
+	if len(health.Redirects) > 0 && !sameResource(requestedURL, health.FinalURL) {
+		health.Verdict, health.Reason = LinkMoved, "The link redirects to a different page"
+		return
+	}
+
+	health.Verdict = LinkLive
+	if !strings.Contains(health.ContentType, "html") && health.ContentType != "" {
+		return
+	}
+
+	body, err := fetcher.readBody(resp)
+	if err != nil {
+		// Oversized or unreadable pages still loaded, so treat them as live
+		return
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	if fields, ok := extractJobPostingSchema(doc); ok && fields.Deadline != "" {
+		if deadline, ok := parseDeadline(fields.Deadline); ok && deadline.Before(time.Now()) {
+			health.Verdict, health.Reason = LinkExpired, "The posting's application deadline has passed"
+			return
+		}
+	}
+
+	host := ""
+	if u, err := url.Parse(health.FinalURL); err == nil {
+		host = u.Hostname()
+	}
+	if pattern, ok := LoadExpiredPagePatterns().Match(host, w.pageSummaryText(doc)); ok {
+		health.Verdict, health.Reason = LinkExpired, "The page says the posting is closed"
+		log.Printf("Link %s matched expired pattern %s", health.FinalURL, pattern)
+	}
+}
+
+// pageSummaryText returns the title, headings and main content of a page,
+// which is where closed-posting notices appear. Headings are only taken from
+// the main content (<main> or <article> when the page has one, otherwise the
+// best-scoring content block) after boilerplate such as <aside> and <nav> is
+// stripped, so other listings' labels in sidebars do not trigger a match.
+func (w *WebProcessor) pageSummaryText(doc *html.Node) string {
+	var parts []string
+	if title := w.findMainContentNode(doc, []string{"title"}); title != nil {
+		parts = append(parts, collapseSpace(nodeText(title)))
+	}
+
+	stripBoilerplate(doc)
+	root := w.findMainContentNode(doc, []string{"main", "article"})
+	if root == nil {
+		root = doc
+	}
+	content := w.findBestContentNode(root)
+	scope := content
+	if root != doc {
+		scope = root
+	}
+
+	if scope != nil {
+		var walk func(*html.Node)
+		walk = func(n *html.Node) {
+			if n.Type == html.ElementNode && (n.Data == "h1" || n.Data == "h2") {
+				parts = append(parts, collapseSpace(nodeText(n)))
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+		walk(scope)
+	}
+	if content != nil {
+		parts = append(parts, collapseSpace(w.extractText(content)))
+	}
+	text := strings.Join(parts, "\n")
+	if len(text) > linkCheckTextChars {
+		text = text[:linkCheckTextChars]
+	}
+	return text
+}
+
+// sameResource reports whether a redirect only normalised the URL (scheme,
+// www prefix or trailing slash) rather than sending the user elsewhere
+func sameResource(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	hostA := strings.TrimPrefix(strings.ToLower(ua.Hostname()), "www.")
+	hostB := strings.TrimPrefix(strings.ToLower(ub.Hostname()), "www.")
+	return hostA == hostB &&
+		strings.TrimSuffix(ua.EscapedPath(), "/") == strings.TrimSuffix(ub.EscapedPath(), "/") &&
+		ua.RawQuery == ub.RawQuery
+}
+
+// parseDeadline reads the date formats used for validThrough
+func parseDeadline(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			if layout == "2006-01-02" {
+				// A bare date means applications are open all that day
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
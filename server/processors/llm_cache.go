@@ -0,0 +1,500 @@
+package processors
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"easy-apply/models"
+)
+
+// LLM cache bounds and housekeeping
+const (
+	defaultLLMCacheItems  = 1000
+	llmCacheSweepInterval = time.Minute
+	llmCacheWriteTimeout  = 5 * time.Second
+	// llmCacheKeyVersion changes whenever the key derivation does
+	llmCacheKeyVersion = "v1"
+)
+
+// LLMCache stores validated LLM replies by LLMCacheKey.
+// Get returns nil without an error on a cache miss.
+type LLMCache interface {
+	Get(ctx context.Context, key string) (*models.CachedLLMResponse, error)
+	Set(ctx context.Context, key string, entry *models.CachedLLMResponse) error
+}
+
+var (
+	llmCacheMu sync.RWMutex
+	llmCache   LLMCache
+)
+
+// InitializeLLMCache sets the cache used by routers that have none of their own.
+// A nil cache disables response caching.
+func InitializeLLMCache(cache LLMCache) {
+	llmCacheMu.Lock()
+	defer llmCacheMu.Unlock()
+	llmCache = cache
+}
+
+func defaultLLMCache() LLMCache {
+	llmCacheMu.RLock()
+	defer llmCacheMu.RUnlock()
+	return llmCache
+}
+
+// LLMCacheKey is the SHA-256 of everything that shapes a reply: task,
+// provider, model, prompt version, messages, sampling settings and schema
+func LLMCacheKey(task LLMTask, provider string, req LLMRequest, jsonMode bool) string {
+	var schema any
+	if req.Schema != nil {
+		schema = req.Schema
+	}
+	// Marshalling sorts map keys, so equal requests always hash the same
+	payload, _ := json.Marshal(struct {
+		Version, Task, Provider, Model, PromptVersion, System string
+		Messages                                              []LLMMessage
+		Temperature, TopP                                     float64
+		MaxTokens                                             int
+		JSON                                                  bool
+		Schema                                                any
+	}{
+		llmCacheKeyVersion, string(task), provider, req.Model, req.PromptVersion, req.System,
+		req.Messages, req.Temperature, req.TopP, req.MaxTokens, jsonMode, schema,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheMetrics counts response cache lookups for one task
+type CacheMetrics struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Errors int64 `json:"errors"` // failed reads and writes; a failed read counts as a miss too
+}
+
+var (
+	cacheMetricsMu sync.Mutex
+	cacheMetrics   = make(map[LLMTask]*CacheMetrics)
+)
+
+func recordCacheMetric(task LLMTask, update func(m *CacheMetrics)) {
+	cacheMetricsMu.Lock()
+	defer cacheMetricsMu.Unlock()
+	m, ok := cacheMetrics[task]
+	if !ok {
+		m = &CacheMetrics{}
+		cacheMetrics[task] = m
+	}
+	update(m)
+}
+
+// LLMCacheMetrics returns a snapshot of the response cache counters per task
+func LLMCacheMetrics() map[LLMTask]CacheMetrics {
+	cacheMetricsMu.Lock()
+	defer cacheMetricsMu.Unlock()
+	snapshot := make(map[LLMTask]CacheMetrics, len(cacheMetrics))
+	for task, m := range cacheMetrics {
+		snapshot[task] = *m
+	}
+	return snapshot
+}
+
+// cacheLookup returns the cached reply for key, if there is a live one
+func (r *LLMRouter) cacheLookup(ctx context.Context, task LLMTask, cache LLMCache, key string) *LLMResponse {
+	entry, err := cache.Get(ctx, key)
+	if err != nil {
+		log.Printf("LLM cache read for task %s failed: %v", task, err)
+	}
+	if err != nil || entry == nil || !time.Now().Before(entry.ExpiresAt) {
+		recordCacheMetric(task, func(m *CacheMetrics) {
+			m.Misses++
+			if err != nil {
+				m.Errors++
+			}
+		})
+		return nil
+	}
+	recordCacheMetric(task, func(m *CacheMetrics) { m.Hits++ })
+	return &LLMResponse{Text: entry.Text, Provider: entry.Provider, Model: entry.Model, Cached: true}
+}
+
+// cacheStore saves a validated reply under key. The write outlives a
+// cancelled request so a finished generation is not wasted.
+func (r *LLMRouter) cacheStore(ctx context.Context, task LLMTask, cache LLMCache, key string, req LLMRequest, resp *LLMResponse, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), llmCacheWriteTimeout)
+	defer cancel()
+	now := time.Now().UTC()
+	entry := &models.CachedLLMResponse{
+		Task:          string(task),
+		Provider:      resp.Provider,
+		Model:         resp.Model,
+		PromptVersion: req.PromptVersion,
+		Text:          resp.Text,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(ttl),
+	}
+	if entry.Model == "" {
+		entry.Model = req.Model
+	}
+	if err := cache.Set(ctx, key, entry); err != nil {
+		recordCacheMetric(task, func(m *CacheMetrics) { m.Errors++ })
+		log.Printf("LLM cache write for task %s failed: %v", task, err)
+	}
+}
+
+// cacheFor returns the cache to use for task, or nil when the task is not cached
+func (r *LLMRouter) cacheFor(config LLMTaskConfig) LLMCache {
+	if config.CacheTTL <= 0 {
+		return nil
+	}
+	if r.Cache != nil {
+		return r.Cache
+	}
+	return defaultLLMCache()
+}
+
+// lruIndex orders cache keys by last use and drops the oldest beyond maxItems
+type lruIndex struct {
+	maxItems int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	expiresAt time.Time
+	value     *models.CachedLLMResponse // nil for caches that keep values elsewhere, such as the disk cache
+}
+
+func newLRUIndex(maxItems int) *lruIndex {
+	if maxItems <= 0 {
+		maxItems = defaultLLMCacheItems
+	}
+	return &lruIndex{maxItems: maxItems, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the entry for key and marks it most recently used
+func (l *lruIndex) get(key string) *lruEntry {
+	elem, ok := l.items[key]
+	if !ok {
+		return nil
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry)
+}
+
+// put adds or replaces an entry and returns the keys evicted to make room
+func (l *lruIndex) put(entry *lruEntry) []string {
+	if elem, ok := l.items[entry.key]; ok {
+		elem.Value = entry
+		l.order.MoveToFront(elem)
+		return nil
+	}
+	l.items[entry.key] = l.order.PushFront(entry)
+	var evicted []string
+	for l.order.Len() > l.maxItems {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		key := oldest.Value.(*lruEntry).key
+		delete(l.items, key)
+		evicted = append(evicted, key)
+	}
+	return evicted
+}
+
+func (l *lruIndex) remove(key string) {
+	if elem, ok := l.items[key]; ok {
+		l.order.Remove(elem)
+		delete(l.items, key)
+	}
+}
+
+// removeExpired drops entries past their expiry and returns their keys
+func (l *lruIndex) removeExpired(now time.Time) []string {
+	var expired []string
+	for key, elem := range l.items {
+		if !now.Before(elem.Value.(*lruEntry).expiresAt) {
+			l.order.Remove(elem)
+			delete(l.items, key)
+			expired = append(expired, key)
+		}
+	}
+	return expired
+}
+
+// sweeper runs fn every interval until stopped
+type sweeper struct {
+	stop chan struct{}
+	once sync.Once
+}
+
+func startSweeper(interval time.Duration, fn func()) *sweeper {
+	s := &sweeper{stop: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *sweeper) close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// MemoryLLMCache is an in-process LLMCache holding at most a fixed number of
+// replies, evicting the least recently used. Expired replies are removed in
+// the background.
+type MemoryLLMCache struct {
+	mu      sync.Mutex
+	index   *lruIndex
+	sweeper *sweeper
+}
+
+// NewMemoryLLMCache creates an empty in-memory cache. maxItems <= 0 uses the default bound.
+func NewMemoryLLMCache(maxItems int) *MemoryLLMCache {
+	c := &MemoryLLMCache{index: newLRUIndex(maxItems)}
+	c.sweeper = startSweeper(llmCacheSweepInterval, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.index.removeExpired(time.Now())
+	})
+	return c
+}
+
+// Get returns the cached reply for key, or nil on a miss.
+func (c *MemoryLLMCache) Get(ctx context.Context, key string) (*models.CachedLLMResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.index.get(key)
+	if entry == nil {
+		return nil, nil
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		c.index.remove(key)
+		return nil, nil
+	}
+	value := *entry.value
+	return &value, nil
+}
+
+// Set stores a reply under key, evicting the least recently used when full.
+func (c *MemoryLLMCache) Set(ctx context.Context, key string, entry *models.CachedLLMResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value := *entry
+	c.index.put(&lruEntry{key: key, expiresAt: entry.ExpiresAt, value: &value})
+	return nil
+}
+
+// Close stops the background expiry.
+func (c *MemoryLLMCache) Close() {
+	c.sweeper.close()
+}
+
+// DiskLLMCache is an LLMCache that keeps one JSON file per reply in a
+// directory, so replies survive restarts of a single instance. It holds at
+// most a fixed number of files, evicting the least recently used, and removes
+// expired files in the background.
+type DiskLLMCache struct {
+	dir     string
+	mu      sync.Mutex
+	index   *lruIndex
+	sweeper *sweeper
+}
+
+// NewDiskLLMCache opens or creates a disk cache in dir, indexing the replies
+// already there. maxItems <= 0 uses the default bound.
+func NewDiskLLMCache(dir string, maxItems int) (*DiskLLMCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create LLM cache directory: %w", err)
+	}
+	c := &DiskLLMCache{dir: dir, index: newLRUIndex(maxItems)}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	c.sweeper = startSweeper(llmCacheSweepInterval, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, key := range c.index.removeExpired(time.Now()) {
+			c.removeFile(key)
+		}
+	})
+	return c, nil
+}
+
+// load indexes the files in the cache directory, least recently used first
+func (c *DiskLLMCache) load() error {
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list LLM cache directory: %w", err)
+	}
+	type indexed struct {
+		key       string
+		usedAt    time.Time
+		expiresAt time.Time
+	}
+	var entries []indexed
+	now := time.Now()
+	for _, path := range files {
+		key := strings.TrimSuffix(filepath.Base(path), ".json")
+		entry, err := c.read(key)
+		if err != nil || !now.Before(entry.ExpiresAt) {
+			os.Remove(path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, indexed{key: key, usedAt: info.ModTime(), expiresAt: entry.ExpiresAt})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].usedAt.Before(entries[j].usedAt) })
+	for _, entry := range entries {
+		for _, evicted := range c.index.put(&lruEntry{key: entry.key, expiresAt: entry.expiresAt}) {
+			c.removeFile(evicted)
+		}
+	}
+	return nil
+}
+
+func (c *DiskLLMCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *DiskLLMCache) read(key string) (*models.CachedLLMResponse, error) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+	var entry models.CachedLLMResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode LLM cache entry %s: %w", key, err)
+	}
+	return &entry, nil
+}
+
+func (c *DiskLLMCache) removeFile(key string) {
+	if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Could not remove LLM cache file %s: %v", key, err)
+	}
+}
+
+// Get returns the cached reply for key, or nil on a miss.
+func (c *DiskLLMCache) Get(ctx context.Context, key string) (*models.CachedLLMResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	indexed := c.index.get(key)
+	if indexed == nil {
+		return nil, nil
+	}
+	if !time.Now().Before(indexed.expiresAt) {
+		c.index.remove(key)
+		c.removeFile(key)
+		return nil, nil
+	}
+	entry, err := c.read(key)
+	if err != nil {
+		c.index.remove(key)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// The modification time records last use, so the order survives a restart
+	now := time.Now()
+	os.Chtimes(c.path(key), now, now)
+	return entry, nil
+}
+
+// Set stores a reply under key, evicting the least recently used when full.
+func (c *DiskLLMCache) Set(ctx context.Context, key string, entry *models.CachedLLMResponse) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode LLM cache entry: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Write then rename so a crash never leaves a truncated entry
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write LLM cache entry: %w", err)
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write LLM cache entry: %w", err)
+	}
+
+	for _, evicted := range c.index.put(&lruEntry{key: key, expiresAt: entry.ExpiresAt}) {
+		c.removeFile(evicted)
+	}
+	return nil
+}
+
+// Close stops the background expiry.
+func (c *DiskLLMCache) Close() {
+	c.sweeper.close()
+}
+
+// TieredLLMCache reads through a fast front cache to a slower shared one,
+// such as memory in front of Firestore. Writes go to both.
+type TieredLLMCache struct {
+	Front LLMCache
+	Back  LLMCache
+}
+
+// NewTieredLLMCache creates a cache that checks front before back.
+func NewTieredLLMCache(front, back LLMCache) *TieredLLMCache {
+	return &TieredLLMCache{Front: front, Back: back}
+}
+
+// Get returns the reply from the first tier that has it, copying a back-tier
+// hit to the front.
+func (c *TieredLLMCache) Get(ctx context.Context, key string) (*models.CachedLLMResponse, error) {
+	if entry, err := c.Front.Get(ctx, key); err == nil && entry != nil {
+		return entry, nil
+	}
+	entry, err := c.Back.Get(ctx, key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	if time.Now().Before(entry.ExpiresAt) {
+		c.Front.Set(ctx, key, entry)
+	}
+	return entry, nil
+}
+
+// Set stores a reply in both tiers.
+func (c *TieredLLMCache) Set(ctx context.Context, key string, entry *models.CachedLLMResponse) error {
+	if err := c.Front.Set(ctx, key, entry); err != nil {
+		return err
+	}
+	return c.Back.Set(ctx, key, entry)
+}
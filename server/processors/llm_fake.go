@@ -0,0 +1,106 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// fakeStreamChunk is the size of the pieces FakeProvider streams
+const fakeStreamChunk = 16
+
+// ErrFakeScriptExhausted is returned when a FakeProvider has no scripted reply left
+var ErrFakeScriptExhausted = errors.New("fake LLM provider has no scripted responses left")
+
+// FakeReply is one scripted outcome of a FakeProvider call
+type FakeReply struct {
+	Text  string
+	Usage LLMUsage
+	Err   error
+}
+
+// FakeProvider is a scriptable LLMProvider for local runs and tests. Replies
+// are consumed in order; once they run out, Handler (if set) answers instead.
+// Every request is recorded in Requests.
+type FakeProvider struct {
+	Handler func(req LLMRequest, jsonMode bool) FakeReply
+
+	mu       sync.Mutex
+	replies  []FakeReply
+	Requests []LLMRequest
+}
+
+// NewFakeProvider creates a fake that answers JSON requests with "{}" and
+// chat requests with a fixed placeholder until replies are scripted
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		Handler: func(req LLMRequest, jsonMode bool) FakeReply {
+			if jsonMode {
+				return FakeReply{Text: "{}"}
+			}
+			return FakeReply{Text: "fake response"}
+		},
+	}
+}
+
+// Script queues replies to be returned by the next calls
+func (p *FakeProvider) Script(replies ...FakeReply) *FakeProvider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replies = append(p.replies, replies...)
+	return p
+}
+
+// Name returns the provider name used in configuration
+func (p *FakeProvider) Name() string { return LLMProviderFake }
+
+// Chat returns the next scripted reply
+func (p *FakeProvider) Chat(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	return p.next(ctx, req, false)
+}
+
+// CompleteJSON returns the next scripted reply
+func (p *FakeProvider) CompleteJSON(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	return p.next(ctx, req, true)
+}
+
+// StreamJSON returns the next scripted reply, delivering it to onText in
+// fakeStreamChunk-byte pieces
+func (p *FakeProvider) StreamJSON(ctx context.Context, req LLMRequest, onText func(text string)) (*LLMResponse, error) {
+	resp, err := p.next(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	for end := fakeStreamChunk; ; end += fakeStreamChunk {
+		if end >= len(resp.Text) {
+			onText(resp.Text)
+			break
+		}
+		onText(resp.Text[:end])
+	}
+	return resp, nil
+}
+
+func (p *FakeProvider) next(ctx context.Context, req LLMRequest, jsonMode bool) (*LLMResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.Requests = append(p.Requests, req)
+	var reply FakeReply
+	switch {
+	case len(p.replies) > 0:
+		reply, p.replies = p.replies[0], p.replies[1:]
+	case p.Handler != nil:
+		reply = p.Handler(req, jsonMode)
+	default:
+		reply = FakeReply{Err: ErrFakeScriptExhausted}
+	}
+	p.mu.Unlock()
+
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+	return &LLMResponse{Text: reply.Text, Provider: LLMProviderFake, Model: req.Model, Usage: reply.Usage}, nil
+}
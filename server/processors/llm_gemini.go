@@ -0,0 +1,224 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"easy-apply/retry"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GeminiProvider implements LLMProvider with the Gemini API
+type GeminiProvider struct {
+	client *genai.Client
+}
+
+// NewGeminiProvider creates a Gemini client from GEMINI_API_KEY
+func NewGeminiProvider(ctx context.Context) (*GeminiProvider, error) {
+	apiKey := os.Getenv("GEMINI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY environment variable not set")
+	}
+	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+	}
+	return &GeminiProvider{client: client}, nil
+}
+
+// Name returns the provider name used in configuration
+func (p *GeminiProvider) Name() string { return LLMProviderGemini }
+
+// Close releases the underlying client
+func (p *GeminiProvider) Close() error {
+	return p.client.Close()
+}
+
+// Chat runs a plain completion
+func (p *GeminiProvider) Chat(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	return p.complete(ctx, req, "")
+}
+
+// CompleteJSON runs a completion with the application/json response type,
+// constrained by the request's schema when it has one
+func (p *GeminiProvider) CompleteJSON(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	return p.complete(ctx, req, "application/json")
+}
+
+// StreamJSON runs CompleteJSON as a streaming completion
+func (p *GeminiProvider) StreamJSON(ctx context.Context, req LLMRequest, onText func(text string)) (*LLMResponse, error) {
+	session, last, err := p.session(req, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	iter := session.SendMessageStream(ctx, last)
+	var text strings.Builder
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gemini api error: %w", classifyGeminiError(err))
+		}
+		if chunk := geminiText(resp); chunk != "" {
+			text.WriteString(chunk)
+			onText(text.String())
+		}
+	}
+	return geminiResponse(req, text.String(), iter.MergedResponse()), nil
+}
+
+func (p *GeminiProvider) complete(ctx context.Context, req LLMRequest, mimeType string) (*LLMResponse, error) {
+	session, last, err := p.session(req, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := session.SendMessage(ctx, last)
+	if err != nil {
+		return nil, fmt.Errorf("gemini api error: %w", classifyGeminiError(err))
+	}
+	text := geminiText(resp)
+	if text == "" {
+		return nil, fmt.Errorf("%w: no candidates returned or content is empty", ErrEmptyLLMResponse)
+	}
+	return geminiResponse(req, text, resp), nil
+}
+
+// session configures a model for req and returns a chat session holding
+// every turn but the last, which is returned for sending
+func (p *GeminiProvider) session(req LLMRequest, mimeType string) (*genai.ChatSession, genai.Text, error) {
+	if len(req.Messages) == 0 {
+		return nil, "", retry.NewError(retry.ClassInvalidRequest, errors.New("gemini request has no messages"))
+	}
+
+	model := p.client.GenerativeModel(req.Model)
+	if req.System != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(req.System)}}
+	}
+	model.ResponseMIMEType = mimeType
+	if mimeType == "application/json" && req.Schema != nil {
+		model.ResponseSchema = geminiSchema(req.Schema.Schema)
+	}
+	if req.Temperature != 0 {
+		model.SetTemperature(float32(req.Temperature))
+	}
+	if req.MaxTokens != 0 {
+		model.SetMaxOutputTokens(int32(req.MaxTokens))
+	}
+	if req.TopP != 0 {
+		model.SetTopP(float32(req.TopP))
+	}
+
+	session := model.StartChat()
+	for _, m := range req.Messages[:len(req.Messages)-1] {
+		role := "user"
+		if m.Role == LLMRoleAssistant {
+			role = "model"
+		}
+		session.History = append(session.History, &genai.Content{Role: role, Parts: []genai.Part{genai.Text(m.Content)}})
+	}
+	return session, genai.Text(req.Messages[len(req.Messages)-1].Content), nil
+}
+
+// geminiText joins the text parts of the first candidate
+func geminiText(resp *genai.GenerateContentResponse) string {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			text.WriteString(string(t))
+		}
+	}
+	return text.String()
+}
+
+// geminiResponse builds an LLMResponse with the usage reported on resp
+func geminiResponse(req LLMRequest, text string, resp *genai.GenerateContentResponse) *LLMResponse {
+	result := &LLMResponse{Text: text, Provider: LLMProviderGemini, Model: req.Model}
+	if resp != nil && resp.UsageMetadata != nil {
+		result.Usage = LLMUsage{
+			PromptTokens:     int(resp.UsageMetadata.PromptTokenCount),
+			CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+			TotalTokens:      int(resp.UsageMetadata.TotalTokenCount),
+		}
+	}
+	return result
+}
+
+// geminiSchema converts a JSON Schema to Gemini's schema type. Gemini has no
+// union types, so ["T", "null"] becomes a nullable T; keywords Gemini does
+// not support are dropped and left to validateSchema.
+func geminiSchema(schema map[string]any) *genai.Schema {
+	out := &genai.Schema{}
+	for _, t := range schemaTypes(schema) {
+		if t == "null" {
+			out.Nullable = true
+			continue
+		}
+		if out.Type == genai.TypeUnspecified {
+			out.Type = geminiType(t)
+		}
+	}
+	if description, ok := schema["description"].(string); ok {
+		out.Description = description
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		for _, value := range enum {
+			out.Enum = append(out.Enum, fmt.Sprint(value))
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		out.Items = geminiSchema(items)
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		out.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, prop := range properties {
+			if m, ok := prop.(map[string]any); ok {
+				out.Properties[name] = geminiSchema(m)
+			}
+		}
+	}
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			out.Required = append(out.Required, fmt.Sprint(name))
+		}
+	}
+	return out
+}
+
+func geminiType(t string) genai.Type {
+	switch t {
+	case "string":
+		return genai.TypeString
+	case "number":
+		return genai.TypeNumber
+	case "integer":
+		return genai.TypeInteger
+	case "boolean":
+		return genai.TypeBoolean
+	case "array":
+		return genai.TypeArray
+	case "object":
+		return genai.TypeObject
+	}
+	return genai.TypeUnspecified
+}
+
+// classifyGeminiError marks blocked prompts and replies as content filter
+// failures; API errors are classified by their gRPC status
+func classifyGeminiError(err error) error {
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		return retry.NewError(retry.ClassContentFilter, err)
+	}
+	return err
+}
@@ -0,0 +1,165 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"easy-apply/retry"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/shared"
+)
+
+// OpenAIProvider implements LLMProvider with the OpenAI chat completions API
+type OpenAIProvider struct {
+	client openai.Client
+}
+
+// NewOpenAIProvider creates a provider on the shared OpenAI client
+func NewOpenAIProvider() (*OpenAIProvider, error) {
+	if err := initializeClient(); err != nil {
+		return nil, fmt.Errorf("failed to initialize OpenAI client: %w", err)
+	}
+	return &OpenAIProvider{client: client}, nil
+}
+
+// Name returns the provider name used in configuration
+func (p *OpenAIProvider) Name() string { return LLMProviderOpenAI }
+
+// Chat runs a plain chat completion
+func (p *OpenAIProvider) Chat(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	return p.complete(ctx, req, openai.ChatCompletionNewParamsResponseFormatUnion{})
+}
+
+// CompleteJSON runs a chat completion with a strict JSON schema when the
+// request has one, or in JSON object mode otherwise
+func (p *OpenAIProvider) CompleteJSON(ctx context.Context, req LLMRequest) (*LLMResponse, error) {
+	return p.complete(ctx, req, jsonResponseFormat(req))
+}
+
+// StreamJSON runs CompleteJSON as a streaming completion
+func (p *OpenAIProvider) StreamJSON(ctx context.Context, req LLMRequest, onText func(text string)) (*LLMResponse, error) {
+	params := buildOpenAIParams(req, jsonResponseFormat(req))
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" && len(acc.Choices) > 0 {
+			onText(acc.Choices[0].Message.Content)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("error streaming chat completion: %w", classifyOpenAIError(err))
+	}
+	return openAIResponse(&acc.ChatCompletion)
+}
+
+func (p *OpenAIProvider) complete(ctx context.Context, req LLMRequest, format openai.ChatCompletionNewParamsResponseFormatUnion) (*LLMResponse, error) {
+	chatCompletion, err := p.client.Chat.Completions.New(ctx, buildOpenAIParams(req, format))
+	if err != nil {
+		return nil, fmt.Errorf("error creating chat completion: %w", classifyOpenAIError(err))
+	}
+	return openAIResponse(chatCompletion)
+}
+
+// jsonResponseFormat selects strict schema mode when the request has a schema
+func jsonResponseFormat(req LLMRequest) openai.ChatCompletionNewParamsResponseFormatUnion {
+	if req.Schema != nil {
+		return openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   req.Schema.Name,
+					Schema: providerSchema(req.Schema.Schema),
+					Strict: openai.Bool(true),
+				},
+			},
+		}
+	}
+	return openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
+	}
+}
+
+// buildOpenAIParams converts a request to chat completion parameters
+func buildOpenAIParams(req LLMRequest, format openai.ChatCompletionNewParamsResponseFormatUnion) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Model:          req.Model,
+		Messages:       buildOpenAIMessages(req),
+		ResponseFormat: format,
+	}
+	if req.Temperature != 0 {
+		params.Temperature = openai.Float(req.Temperature)
+	}
+	if req.MaxTokens != 0 {
+		params.MaxTokens = openai.Int(int64(req.MaxTokens))
+	}
+	if req.TopP != 0 {
+		params.TopP = openai.Float(req.TopP)
+	}
+	return params
+}
+
+// openAIResponse extracts the reply text and usage from a completion
+func openAIResponse(chatCompletion *openai.ChatCompletion) (*LLMResponse, error) {
+	if len(chatCompletion.Choices) == 0 {
+		return nil, fmt.Errorf("%w: no response choices available", ErrEmptyLLMResponse)
+	}
+	if chatCompletion.Choices[0].FinishReason == "content_filter" {
+		return nil, retry.NewError(retry.ClassContentFilter, errors.New("completion stopped by the OpenAI content filter"))
+	}
+	return &LLMResponse{
+		Text:     chatCompletion.Choices[0].Message.Content,
+		Provider: LLMProviderOpenAI,
+		Model:    chatCompletion.Model,
+		Usage: LLMUsage{
+			PromptTokens:     int(chatCompletion.Usage.PromptTokens),
+			CompletionTokens: int(chatCompletion.Usage.CompletionTokens),
+			TotalTokens:      int(chatCompletion.Usage.TotalTokens),
+		},
+	}, nil
+}
+
+// buildOpenAIMessages converts a request to the OpenAI message array
+func buildOpenAIMessages(req LLMRequest) []openai.ChatCompletionMessageParamUnion {
+	var messages []openai.ChatCompletionMessageParamUnion
+	if req.System != "" {
+		messages = append(messages, openai.SystemMessage(req.System))
+	}
+	for _, m := range req.Messages {
+		if m.Role == LLMRoleAssistant {
+			messages = append(messages, openai.AssistantMessage(m.Content))
+		} else {
+			messages = append(messages, openai.UserMessage(m.Content))
+		}
+	}
+	return messages
+}
+
+// classifyOpenAIError marks API errors with their retry class, using the
+// retry-after-ms or Retry-After header on rate limits
+func classifyOpenAIError(err error) error {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	classified := &retry.Error{Class: retry.ClassifyStatus(apiErr.StatusCode), StatusCode: apiErr.StatusCode, Err: err}
+	if apiErr.Code == "content_policy_violation" || apiErr.Code == "content_filter" {
+		classified.Class = retry.ClassContentFilter
+	}
+	if apiErr.Response != nil {
+		if ms, convErr := strconv.Atoi(apiErr.Response.Header.Get("retry-after-ms")); convErr == nil {
+			classified.RetryAfter = time.Duration(ms) * time.Millisecond
+		} else {
+			classified.RetryAfter = retry.ParseRetryAfter(apiErr.Response.Header.Get("Retry-After"))
+		}
+	}
+	return classified
+}
@@ -0,0 +1,453 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"easy-apply/constants"
+	"easy-apply/retry"
+)
+
+// Errors returned by LLM providers and the router
+var (
+	ErrUnknownLLMProvider = errors.New("unknown LLM provider")
+	ErrEmptyLLMResponse   = errors.New("empty LLM response")
+	ErrInvalidLLMJSON     = errors.New("LLM response is not valid JSON")
+)
+
+// defaultMaxRepairs bounds how many times an invalid JSON reply is sent back for correction
+const defaultMaxRepairs = 2
+
+// LLMTask names a workload whose provider and model are chosen by configuration
+type LLMTask string
+
+const (
+	LLMTaskResumeGeneration  LLMTask = "resume_generation"
+	LLMTaskSubjectExtraction LLMTask = "subject_extraction"
+	LLMTaskRecommendation    LLMTask = "recommendation_analysis"
+	LLMTaskListingParsing    LLMTask = "listing_parsing"
+	LLMTaskCoverLetter       LLMTask = "cover_letter"
+)
+
+// Provider names accepted in LLM_<TASK>_PROVIDER
+const (
+	LLMProviderOpenAI = "openai"
+	LLMProviderGemini = "gemini"
+	LLMProviderFake   = "fake"
+)
+
+// Message roles
+const (
+	LLMRoleUser      = "user"
+	LLMRoleAssistant = "assistant"
+)
+
+// LLMMessage is one turn of a conversation
+type LLMMessage struct {
+	Role    string
+	Content string
+}
+
+// LLMRequest is a provider-neutral completion request. Zero-valued sampling
+// fields are left to the provider's defaults. Schema, when set on a JSON
+// request, is passed to the provider's structured-output mode.
+// PromptVersion names the prompt template revision and is part of the
+// response cache key.
+type LLMRequest struct {
+	Model         string
+	System        string
+	Messages      []LLMMessage
+	Temperature   float64
+	MaxTokens     int
+	TopP          float64
+	Schema        *JSONSchema
+	PromptVersion string
+}
+
+// LLMUsage reports the tokens consumed by one completion
+type LLMUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// LLMResponse is the text of a completion and the tokens it used. Cached
+// replies report no usage. PromptVersion is copied from the request.
+type LLMResponse struct {
+	Text          string
+	Provider      string
+	Model         string
+	Usage         LLMUsage
+	Cached        bool
+	PromptVersion string
+}
+
+// LLMProvider is a chat completion backend. CompleteJSON asks the provider
+// for its JSON output mode; callers still validate the result.
+type LLMProvider interface {
+	Name() string
+	Chat(ctx context.Context, req LLMRequest) (*LLMResponse, error)
+	CompleteJSON(ctx context.Context, req LLMRequest) (*LLMResponse, error)
+}
+
+// LLMTaskConfig is the provider, model and sampling settings for a task.
+// Replies are cached for CacheTTL; zero disables caching for the task.
+type LLMTaskConfig struct {
+	Provider    string
+	Model       string
+	Temperature float64
+	MaxTokens   int
+	TopP        float64
+	Timeout     time.Duration
+	CacheTTL    time.Duration
+}
+
+// DefaultLLMTaskConfigs keeps each task on the vendor it was built against
+var DefaultLLMTaskConfigs = map[LLMTask]LLMTaskConfig{
+	// Resumes are not cached: regenerating is how a user gets a new document
+	LLMTaskResumeGeneration: {
+		Provider: LLMProviderOpenAI, Model: constants.ResumeGenModel,
+		Temperature: 0.3, MaxTokens: 8000, TopP: 1.0, Timeout: defaultTimeout,
+	},
+	LLMTaskSubjectExtraction: {
+		Provider: LLMProviderOpenAI, Model: constants.SubjectGenModel,
+		Temperature: 0.7, MaxTokens: 64, TopP: 0.9, Timeout: defaultTimeout,
+		CacheTTL: 7 * 24 * time.Hour,
+	},
+	LLMTaskRecommendation: {
+		Provider: LLMProviderOpenAI, Model: constants.RECOMMENDATIONS_MODEL,
+		Temperature: 0.5, MaxTokens: 512, TopP: 1.0, Timeout: defaultTimeout,
+		CacheTTL: 24 * time.Hour,
+	},
+	// Cover letters are not cached: asking again is how a user gets a new draft
+	LLMTaskCoverLetter: {
+		Provider: LLMProviderOpenAI, Model: constants.ResumeGenModel,
+		Temperature: 0.7, MaxTokens: 2000, TopP: 1.0, Timeout: defaultTimeout,
+	},
+	LLMTaskListingParsing: {
+		Provider: LLMProviderGemini, Model: constants.GeminiModelName,
+		Temperature: 0.7, MaxTokens: 4096, Timeout: 200 * time.Second,
+		CacheTTL: 7 * 24 * time.Hour,
+	},
+}
+
+// LoadLLMTaskConfigs applies LLM_<TASK>_PROVIDER, LLM_<TASK>_MODEL,
+// LLM_<TASK>_TIMEOUT and LLM_<TASK>_CACHE_TTL (e.g. LLM_LISTING_PARSING_MODEL)
+// over the defaults; a cache TTL of 0 turns caching off for the task.
+// Moving a task to another provider without naming a model uses that
+// provider's default model.
+func LoadLLMTaskConfigs() map[LLMTask]LLMTaskConfig {
+	configs := make(map[LLMTask]LLMTaskConfig, len(DefaultLLMTaskConfigs))
+	for task, config := range DefaultLLMTaskConfigs {
+		prefix := "LLM_" + strings.ToUpper(string(task)) + "_"
+		if provider := strings.ToLower(strings.TrimSpace(os.Getenv(prefix + "PROVIDER"))); provider != "" && provider != config.Provider {
+			config.Provider = provider
+			config.Model = defaultModelFor(provider)
+		}
+		if model := strings.TrimSpace(os.Getenv(prefix + "MODEL")); model != "" {
+			config.Model = model
+		}
+		if timeout, err := time.ParseDuration(os.Getenv(prefix + "TIMEOUT")); err == nil && timeout > 0 {
+			config.Timeout = timeout
+		}
+		if ttl, err := time.ParseDuration(os.Getenv(prefix + "CACHE_TTL")); err == nil && ttl >= 0 {
+			config.CacheTTL = ttl
+		}
+		configs[task] = config
+	}
+	return configs
+}
+
+// defaultModelFor is the model used when a task is moved to provider without naming one
+func defaultModelFor(provider string) string {
+	switch provider {
+	case LLMProviderOpenAI:
+		return constants.ResumeGenModel
+	case LLMProviderGemini:
+		return constants.GeminiModelName
+	}
+	return ""
+}
+
+// LLMRouter sends each task to its configured provider, with a per-attempt
+// timeout and retries under the Retry policy. JSON replies are cleaned and
+// validated against the task's schema; invalid ones are sent back to the
+// model with the violations, up to MaxRepairs times. Valid replies are
+// cached for the task's CacheTTL.
+type LLMRouter struct {
+	Providers  map[string]LLMProvider
+	Tasks      map[LLMTask]LLMTaskConfig
+	Retry      retry.Policy
+	MaxRepairs int
+	// Recorder receives token usage for every call; when nil, the recorder
+	// set by InitializeLLMUsageRecorder is used
+	Recorder LLMUsageRecorder
+	// Cache stores replies; when nil, the cache set by InitializeLLMCache is used
+	Cache LLMCache
+}
+
+// NewLLMRouter creates a router over the given providers and task configs
+func NewLLMRouter(providers map[string]LLMProvider, tasks map[LLMTask]LLMTaskConfig) *LLMRouter {
+	return &LLMRouter{
+		Providers:  providers,
+		Tasks:      tasks,
+		Retry:      retry.Default,
+		MaxRepairs: defaultMaxRepairs,
+	}
+}
+
+// NewLLMRouterFromEnv loads task configs from the environment and creates
+// only the providers they reference, so a deployment needs API keys only
+// for the vendors it uses. When only is given, the router serves just those tasks.
+func NewLLMRouterFromEnv(ctx context.Context, only ...LLMTask) (*LLMRouter, error) {
+	tasks := LoadLLMTaskConfigs()
+	if len(only) > 0 {
+		selected := make(map[LLMTask]LLMTaskConfig, len(only))
+		for _, task := range only {
+			selected[task] = tasks[task]
+		}
+		tasks = selected
+	}
+	providers := make(map[string]LLMProvider)
+	for task, config := range tasks {
+		if _, ok := providers[config.Provider]; ok {
+			continue
+		}
+		var provider LLMProvider
+		var err error
+		switch config.Provider {
+		case LLMProviderOpenAI:
+			provider, err = NewOpenAIProvider()
+		case LLMProviderGemini:
+			provider, err = NewGeminiProvider(ctx)
+		case LLMProviderFake:
+			provider = NewFakeProvider()
+		default:
+			err = fmt.Errorf("%w %q for task %s", ErrUnknownLLMProvider, config.Provider, task)
+		}
+		if err != nil {
+			return nil, err
+		}
+		providers[config.Provider] = provider
+	}
+	for task, config := range tasks {
+		log.Printf("LLM task %s uses %s/%s", task, config.Provider, config.Model)
+	}
+	return NewLLMRouter(providers, tasks), nil
+}
+
+// Chat runs a free-text completion for task
+func (r *LLMRouter) Chat(ctx context.Context, task LLMTask, req LLMRequest) (*LLMResponse, error) {
+	provider, config, err := r.route(task)
+	if err != nil {
+		return nil, err
+	}
+	req = applyTaskConfig(req, config)
+
+	cache := r.cacheFor(config)
+	var cacheKey string
+	if cache != nil {
+		cacheKey = LLMCacheKey(task, provider.Name(), req, false)
+		if cached := r.cacheLookup(ctx, task, cache, cacheKey); cached != nil {
+			cached.PromptVersion = req.PromptVersion
+			return cached, nil
+		}
+	}
+	resp, err := r.callWithRetry(ctx, task, provider, req, config.Timeout, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.PromptVersion = req.PromptVersion
+	if cache != nil {
+		r.cacheStore(ctx, task, cache, cacheKey, req, resp, config.CacheTTL)
+	}
+	return resp, nil
+}
+
+// CompleteJSON runs a structured completion for task and returns a response
+// whose Text is a JSON object matching the task's schema (or req.Schema).
+// Replies that stay invalid return a *SchemaError.
+func (r *LLMRouter) CompleteJSON(ctx context.Context, task LLMTask, req LLMRequest) (*LLMResponse, error) {
+	return r.completeJSON(ctx, task, req, nil)
+}
+
+// Config returns the settings used for task
+func (r *LLMRouter) Config(task LLMTask) LLMTaskConfig {
+	if config, ok := r.Tasks[task]; ok {
+		return config
+	}
+	return DefaultLLMTaskConfigs[task]
+}
+
+// route returns the provider and settings for task
+func (r *LLMRouter) route(task LLMTask) (LLMProvider, LLMTaskConfig, error) {
+	config := r.Config(task)
+	provider, ok := r.Providers[config.Provider]
+	if !ok {
+		return nil, config, fmt.Errorf("%w %q for task %s", ErrUnknownLLMProvider, config.Provider, task)
+	}
+	return provider, config, nil
+}
+
+// completeJSON runs the validate-and-repair loop, streaming each reply to
+// onText when it is set
+func (r *LLMRouter) completeJSON(ctx context.Context, task LLMTask, req LLMRequest, onText func(string)) (*LLMResponse, error) {
+	provider, config, err := r.route(task)
+	if err != nil {
+		return nil, err
+	}
+	req = applyTaskConfig(req, config)
+	if req.Schema == nil {
+		req.Schema = LLMTaskSchemas[task]
+	}
+
+	cache := r.cacheFor(config)
+	var cacheKey string
+	if cache != nil {
+		cacheKey = LLMCacheKey(task, provider.Name(), req, true)
+		if cached := r.cacheLookup(ctx, task, cache, cacheKey); cached != nil {
+			if onText != nil {
+				onText(cached.Text)
+			}
+			cached.PromptVersion = req.PromptVersion
+			return cached, nil
+		}
+	}
+
+	var usage LLMUsage
+	for repair := 0; ; repair++ {
+		resp, err := r.callWithRetry(ctx, task, provider, req, config.Timeout, true, onText)
+		if err != nil {
+			return nil, err
+		}
+		usage = addUsage(usage, resp.Usage)
+
+		cleaned, violations, cause := validateJSONReply(resp.Text, req.Schema)
+		if cause == nil {
+			recordSchemaMetric(task, func(m *SchemaMetrics) {
+				if repair == 0 {
+					m.Valid++
+				} else {
+					m.Repaired++
+				}
+			})
+			resp.Text, resp.Usage, resp.PromptVersion = cleaned, usage, req.PromptVersion
+			if cache != nil {
+				r.cacheStore(ctx, task, cache, cacheKey, req, resp, config.CacheTTL)
+			}
+			return resp, nil
+		}
+
+		recordSchemaMetric(task, func(m *SchemaMetrics) { m.Violations++ })
+		log.Printf("LLM task %s reply %d failed validation: %v (%d violations)", task, repair+1, cause, len(violations))
+		if repair >= r.MaxRepairs {
+			recordSchemaMetric(task, func(m *SchemaMetrics) { m.Failures++ })
+			schemaName := ""
+			if req.Schema != nil {
+				schemaName = req.Schema.Name
+			}
+			return nil, &SchemaError{Task: task, Schema: schemaName, Attempts: repair + 1, Violations: violations, Cause: cause}
+		}
+
+		// Show the model its reply and what was wrong with it
+		messages := make([]LLMMessage, 0, len(req.Messages)+2)
+		messages = append(messages, req.Messages...)
+		req.Messages = append(messages,
+			LLMMessage{Role: LLMRoleAssistant, Content: resp.Text},
+			LLMMessage{Role: LLMRoleUser, Content: repairPrompt(req.Schema, violations)},
+		)
+	}
+}
+
+// callWithRetry calls the provider, retrying failures the Retry policy
+// classifies as transient
+func (r *LLMRouter) callWithRetry(ctx context.Context, task LLMTask, provider LLMProvider, req LLMRequest, timeout time.Duration, jsonMode bool, onText func(string)) (*LLMResponse, error) {
+	op := fmt.Sprintf("LLM task %s on %s/%s", task, provider.Name(), req.Model)
+	return retry.Do(ctx, r.Retry, op, func(attempt int) (*LLMResponse, error) {
+		started := time.Now()
+		resp, err := r.attempt(ctx, provider, req, timeout, jsonMode, onText)
+		r.recordUsage(ctx, task, provider, req, resp, err, time.Since(started))
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	})
+}
+
+// attempt makes one provider call under its own timeout, streaming JSON
+// replies to onText when the provider supports it
+func (r *LLMRouter) attempt(ctx context.Context, provider LLMProvider, req LLMRequest, timeout time.Duration, jsonMode bool, onText func(string)) (*LLMResponse, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var resp *LLMResponse
+	var err error
+	streamer, canStream := provider.(LLMStreamer)
+	switch {
+	case jsonMode && onText != nil && canStream:
+		resp, err = streamer.StreamJSON(ctx, req, onText)
+	case jsonMode:
+		resp, err = provider.CompleteJSON(ctx, req)
+	default:
+		resp, err = provider.Chat(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(resp.Text) == "" {
+		// Returned so the tokens spent are still recorded
+		return resp, ErrEmptyLLMResponse
+	}
+	return resp, nil
+}
+
+// addUsage sums token usage across the calls that produced one reply
+func addUsage(a, b LLMUsage) LLMUsage {
+	return LLMUsage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
+
+// applyTaskConfig fills request fields the caller left unset from the task config
+func applyTaskConfig(req LLMRequest, config LLMTaskConfig) LLMRequest {
+	if req.Model == "" {
+		req.Model = config.Model
+	}
+	if req.Temperature == 0 {
+		req.Temperature = config.Temperature
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = config.MaxTokens
+	}
+	if req.TopP == 0 {
+		req.TopP = config.TopP
+	}
+	return req
+}
+
+// CleanJSONResponse strips Markdown code fences and any text around the
+// outermost JSON object
+func CleanJSONResponse(response string) string {
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+	response = strings.TrimSpace(response)
+
+	firstBrace := strings.Index(response, "{")
+	lastBrace := strings.LastIndex(response, "}")
+	if firstBrace != -1 && lastBrace > firstBrace {
+		response = response[firstBrace : lastBrace+1]
+	}
+	return response
+}
@@ -0,0 +1,361 @@
+package processors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrSchemaViolation is wrapped by SchemaError when a reply parses as JSON
+// but does not match the task's schema
+var ErrSchemaViolation = errors.New("LLM response does not match schema")
+
+// JSONSchema is a named JSON Schema an LLM task's replies must satisfy. Only
+// the subset understood by validateSchema is used: type, properties, required,
+// additionalProperties, items, enum, minLength and minItems.
+type JSONSchema struct {
+	Name   string
+	Schema map[string]any
+}
+
+// SchemaViolation is one mismatch between a reply and its schema
+type SchemaViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v SchemaViolation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// SchemaError reports a reply that was still invalid after the repair
+// attempts ran out. It unwraps to ErrInvalidLLMJSON or ErrSchemaViolation.
+type SchemaError struct {
+	Task       LLMTask
+	Schema     string
+	Attempts   int
+	Violations []SchemaViolation
+	Cause      error
+}
+
+func (e *SchemaError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return fmt.Sprintf("%s: %v after %d attempts (schema %s): %s", e.Task, e.Cause, e.Attempts, e.Schema, strings.Join(parts, "; "))
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Cause
+}
+
+// mustParseSchema builds a JSONSchema from a JSON literal
+func mustParseSchema(name, literal string) *JSONSchema {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(literal), &schema); err != nil {
+		panic(fmt.Sprintf("invalid JSON schema %s: %v", name, err))
+	}
+	return &JSONSchema{Name: name, Schema: schema}
+}
+
+// Schemas are written for OpenAI's strict mode: every property is required
+// and no others are allowed.
+var (
+	resumeGenerationSchema = mustParseSchema("generated_documents", `{
+		"type": "object",
+		"properties": {
+			"generated_resume": {"type": "string", "minLength": 1, "description": "Updated resume as HTML"},
+			"generated_cover_letter": {"type": "string", "minLength": 1, "description": "Cover letter as plain text"}
+		},
+		"required": ["generated_resume", "generated_cover_letter"],
+		"additionalProperties": false
+	}`)
+
+	coverLetterSchema = mustParseSchema("cover_letter", `{
+		"type": "object",
+		"properties": {
+			"generated_cover_letter": {"type": "string", "minLength": 1, "description": "Cover letter as plain text"}
+		},
+		"required": ["generated_cover_letter"],
+		"additionalProperties": false
+	}`)
+
+	subjectExtractionSchema = mustParseSchema("job_subject", `{
+		"type": "object",
+		"properties": {
+			"title": {"type": "string", "minLength": 1, "description": "1-4 word title with the job and company"},
+			"company_name": {"type": "string", "description": "Hiring company, or empty if unknown"}
+		},
+		"required": ["title", "company_name"],
+		"additionalProperties": false
+	}`)
+
+	recommendationSchema = mustParseSchema("resume_classification", `{
+		"type": "object",
+		"properties": {
+			"industry": {"type": "string", "minLength": 1},
+			"domain": {"type": "string", "minLength": 1},
+			"confidence": {"type": "string", "enum": ["high", "medium", "low"]},
+			"reasoning": {"type": "string"}
+		},
+		"required": ["industry", "domain", "confidence", "reasoning"],
+		"additionalProperties": false
+	}`)
+
+	listingParsingSchema = mustParseSchema("job_listing", `{
+		"type": "object",
+		"properties": {
+			"jobTitle": {"type": "string"},
+			"organization": {"type": "string"},
+			"location": {"type": "string"},
+			"grade": {"type": "string"},
+			"reportingTo": {"type": "string"},
+			"responsibleFor": {"type": "string"},
+			"department": {"type": "string"},
+			"purpose": {"type": "string"},
+			"keyResponsibilities": {"type": "array", "items": {"type": "string"}},
+			"requiredQualifications": {"type": "array", "items": {"type": "string"}},
+			"requiredExperience": {"type": "string"},
+			"requiredMemberships": {"type": "string"},
+			"applicationDeadline": {"type": "string"},
+			"contactDetails": {"type": "string"},
+			"additionalNotes": {"type": "string"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"industry": {"type": "string"},
+			"domain": {"type": "string"}
+		},
+		"required": ["jobTitle", "organization", "location", "grade", "reportingTo", "responsibleFor",
+			"department", "purpose", "keyResponsibilities", "requiredQualifications", "requiredExperience",
+			"requiredMemberships", "applicationDeadline", "contactDetails", "additionalNotes", "tags",
+			"industry", "domain"],
+		"additionalProperties": false
+	}`)
+)
+
+// LLMTaskSchemas is the reply schema of each JSON task
+var LLMTaskSchemas = map[LLMTask]*JSONSchema{
+	LLMTaskResumeGeneration:  resumeGenerationSchema,
+	LLMTaskSubjectExtraction: subjectExtractionSchema,
+	LLMTaskRecommendation:    recommendationSchema,
+	LLMTaskListingParsing:    listingParsingSchema,
+	LLMTaskCoverLetter:       coverLetterSchema,
+}
+
+// validateJSONReply cleans text and checks it against schema. It returns the
+// cleaned JSON, or the violations and ErrInvalidLLMJSON/ErrSchemaViolation.
+func validateJSONReply(text string, schema *JSONSchema) (string, []SchemaViolation, error) {
+	cleaned := CleanJSONResponse(text)
+	var value any
+	if err := json.Unmarshal([]byte(cleaned), &value); err != nil {
+		return "", []SchemaViolation{{Path: "$", Message: "not valid JSON: " + err.Error()}}, ErrInvalidLLMJSON
+	}
+	if schema == nil {
+		return cleaned, nil, nil
+	}
+	if violations := validateSchema(value, schema.Schema, "$"); len(violations) > 0 {
+		return "", violations, ErrSchemaViolation
+	}
+	return cleaned, nil, nil
+}
+
+// validateSchema checks value against schema and returns every violation found
+func validateSchema(value any, schema map[string]any, path string) []SchemaViolation {
+	var violations []SchemaViolation
+	fail := func(format string, args ...any) {
+		violations = append(violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !matchesAnyType(value, types) {
+		fail("expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		return violations
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("%v is not one of %v", value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if min, ok := schema["minLength"].(float64); ok && float64(len(strings.TrimSpace(v))) < min {
+			fail("expected at least %d characters", int(min))
+		}
+	case []any:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			fail("expected at least %d items, got %d", int(min), len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				violations = append(violations, validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, present := v[fmt.Sprint(name)]; !present {
+					fail("missing required property %q", name)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propSchema, known := properties[key].(map[string]any)
+			if !known {
+				if allowed, ok := schema["additionalProperties"].(bool); ok && !allowed {
+					fail("unexpected property %q", key)
+				}
+				continue
+			}
+			violations = append(violations, validateSchema(v[key], propSchema, path+"."+key)...)
+		}
+	}
+	return violations
+}
+
+// schemaTypes returns the schema's "type" as a list
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			types = append(types, fmt.Sprint(item))
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesAnyType(value any, types []string) bool {
+	actual := jsonTypeName(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeName names the JSON Schema type of a decoded value
+func jsonTypeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// validatorOnlyKeywords are enforced by validateSchema but not accepted by
+// every provider's structured-output mode
+var validatorOnlyKeywords = map[string]bool{"minLength": true, "minItems": true}
+
+// providerSchema returns a copy of schema without validator-only keywords
+func providerSchema(schema map[string]any) map[string]any {
+	out := make(map[string]any, len(schema))
+	for key, value := range schema {
+		if validatorOnlyKeywords[key] {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			if key == "properties" {
+				props := make(map[string]any, len(v))
+				for name, prop := range v {
+					if m, ok := prop.(map[string]any); ok {
+						props[name] = providerSchema(m)
+					}
+				}
+				out[key] = props
+			} else {
+				out[key] = providerSchema(v)
+			}
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// repairPrompt asks the model to correct its previous reply
+func repairPrompt(schema *JSONSchema, violations []SchemaViolation) string {
+	var sb strings.Builder
+	sb.WriteString("Your previous reply could not be used because:\n")
+	for _, v := range violations {
+		sb.WriteString("- " + v.String() + "\n")
+	}
+	if schema != nil {
+		if literal, err := json.Marshal(providerSchema(schema.Schema)); err == nil {
+			sb.WriteString("\nIt must be a single JSON object matching this JSON Schema:\n")
+			sb.Write(literal)
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString("\nReply again with only the corrected JSON object, without code fences or commentary.")
+	return sb.String()
+}
+
+// SchemaMetrics counts structured-output outcomes for one task
+type SchemaMetrics struct {
+	Valid      int64 `json:"valid"`      // replies valid on the first try
+	Repaired   int64 `json:"repaired"`   // replies valid after one or more repairs
+	Violations int64 `json:"violations"` // invalid replies seen, including repaired ones
+	Failures   int64 `json:"failures"`   // calls that ran out of repair attempts
+}
+
+var (
+	schemaMetricsMu sync.Mutex
+	schemaMetrics   = make(map[LLMTask]*SchemaMetrics)
+)
+
+func recordSchemaMetric(task LLMTask, update func(m *SchemaMetrics)) {
+	schemaMetricsMu.Lock()
+	defer schemaMetricsMu.Unlock()
+	m, ok := schemaMetrics[task]
+	if !ok {
+		m = &SchemaMetrics{}
+		schemaMetrics[task] = m
+	}
+	update(m)
+}
+
+// LLMSchemaMetrics returns a snapshot of the structured-output counters per task
+func LLMSchemaMetrics() map[LLMTask]SchemaMetrics {
+	schemaMetricsMu.Lock()
+	defer schemaMetricsMu.Unlock()
+	snapshot := make(map[LLMTask]SchemaMetrics, len(schemaMetrics))
+	for task, m := range schemaMetrics {
+		snapshot[task] = *m
+	}
+	return snapshot
+}
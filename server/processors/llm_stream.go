@@ -0,0 +1,159 @@
+package processors
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LLMStreamer is implemented by providers that can stream a JSON completion.
+// onText receives the reply accumulated so far after each chunk.
+type LLMStreamer interface {
+	StreamJSON(ctx context.Context, req LLMRequest, onText func(text string)) (*LLMResponse, error)
+}
+
+// StreamJSON is CompleteJSON with the reply streamed to onText as it is
+// generated, when the task's provider supports streaming. Each retry or
+// repair starts a new reply, so onText may see text that does not extend
+// the previous call's. The returned response is validated as in CompleteJSON.
+func (r *LLMRouter) StreamJSON(ctx context.Context, task LLMTask, req LLMRequest, onText func(text string)) (*LLMResponse, error) {
+	return r.completeJSON(ctx, task, req, onText)
+}
+
+// PartialJSONFields returns the top-level string fields of a JSON object that
+// may still be arriving. A value cut off mid-stream is returned up to the
+// last complete character; non-string values are skipped.
+func PartialJSONFields(raw string) map[string]string {
+	fields := make(map[string]string)
+	start := strings.Index(raw, "{")
+	if start < 0 {
+		return fields
+	}
+	s := raw[start+1:]
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,")
+		if s == "" || s[0] != '"' {
+			return fields
+		}
+		key, rest, complete := readJSONString(s)
+		if !complete {
+			return fields
+		}
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		if rest == "" || rest[0] != ':' {
+			return fields
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\r\n")
+		if rest == "" {
+			return fields
+		}
+		if rest[0] == '"' {
+			value, after, complete := readJSONString(rest)
+			fields[key] = value
+			if !complete {
+				return fields
+			}
+			s = after
+			continue
+		}
+		after, complete := skipJSONValue(rest)
+		if !complete {
+			return fields
+		}
+		s = after
+	}
+}
+
+// readJSONString decodes the string literal at the start of s, which may be
+// truncated. It returns the decoded text, the remainder after the closing
+// quote, and whether the literal was complete.
+func readJSONString(s string) (string, string, bool) {
+	var sb strings.Builder
+	i := 1
+	for i < len(s) {
+		switch c := s[i]; c {
+		case '"':
+			return sb.String(), s[i+1:], true
+		case '\\':
+			if i+1 >= len(s) {
+				return sb.String(), "", false
+			}
+			switch esc := s[i+1]; esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'u':
+				if i+6 > len(s) {
+					return sb.String(), "", false
+				}
+				code, err := strconv.ParseUint(s[i+2:i+6], 16, 32)
+				if err != nil {
+					return sb.String(), "", false
+				}
+				r := rune(code)
+				if r >= 0xD800 && r < 0xDC00 {
+					// High surrogate: wait for its pair
+					if i+12 > len(s) {
+						return sb.String(), "", false
+					}
+					if low, err := strconv.ParseUint(s[i+8:i+12], 16, 32); err == nil && s[i+6] == '\\' && s[i+7] == 'u' {
+						r = (r-0xD800)<<10 + (rune(low) - 0xDC00) + 0x10000
+						i += 6
+					}
+				}
+				sb.WriteRune(r)
+				i += 6
+				continue
+			default:
+				sb.WriteByte(esc)
+			}
+			i += 2
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			if size == 1 && !utf8.FullRuneInString(s[i:]) {
+				return sb.String(), "", false
+			}
+			sb.WriteString(s[i : i+size])
+			i += size
+		}
+	}
+	return sb.String(), "", false
+}
+
+// skipJSONValue skips a number, literal, array or object at the start of s
+func skipJSONValue(s string) (string, bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			_, rest, complete := readJSONString(s[i:])
+			if !complete {
+				return "", false
+			}
+			i = len(s) - len(rest) - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return s[i:], true
+			}
+			depth--
+			if depth == 0 {
+				return s[i+1:], true
+			}
+		case ',':
+			if depth == 0 {
+				return s[i:], true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,163 @@
+package processors
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"easy-apply/models"
+)
+
+// LLMUsageScope attributes LLM calls made under a context to a user request
+// or scraper run
+type LLMUsageScope struct {
+	UserID    string
+	HistoryID string
+	RunID     string
+	Endpoint  string
+}
+
+type usageScopeKey struct{}
+
+// WithLLMUsageScope returns a context whose LLM calls are recorded against scope
+func WithLLMUsageScope(ctx context.Context, scope LLMUsageScope) context.Context {
+	return context.WithValue(ctx, usageScopeKey{}, scope)
+}
+
+// LLMUsageScopeFrom returns the scope set by WithLLMUsageScope, if any
+func LLMUsageScopeFrom(ctx context.Context) LLMUsageScope {
+	scope, _ := ctx.Value(usageScopeKey{}).(LLMUsageScope)
+	return scope
+}
+
+// LLMUsageRecorder receives a record for every provider call
+type LLMUsageRecorder interface {
+	RecordLLMUsage(ctx context.Context, record models.LLMUsageRecord)
+}
+
+var (
+	usageRecorderMu sync.RWMutex
+	usageRecorder   LLMUsageRecorder
+)
+
+// InitializeLLMUsageRecorder sets the recorder used by routers that have none of their own
+func InitializeLLMUsageRecorder(recorder LLMUsageRecorder) {
+	usageRecorderMu.Lock()
+	defer usageRecorderMu.Unlock()
+	usageRecorder = recorder
+}
+
+func defaultUsageRecorder() LLMUsageRecorder {
+	usageRecorderMu.RLock()
+	defer usageRecorderMu.RUnlock()
+	return usageRecorder
+}
+
+// ModelPrice is the USD price per million tokens of a model
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input"`
+	OutputPerMillion float64 `json:"output"`
+}
+
+// defaultModelPrices are list prices; override them with LLM_PRICES_FILE
+var defaultModelPrices = map[string]ModelPrice{
+	"gpt-4.1":               {InputPerMillion: 2.00, OutputPerMillion: 8.00},
+	"gpt-4.1-mini":          {InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	"gpt-4.1-nano":          {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gpt-4o":                {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	"gpt-4o-mini":           {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	"gemini-2.0-flash":      {InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	"gemini-2.0-flash-lite": {InputPerMillion: 0.075, OutputPerMillion: 0.30},
+}
+
+// PriceTable maps model names to prices. Models are matched by the longest
+// configured prefix, so dated snapshots such as "gpt-4.1-2025-04-14" use the
+// "gpt-4.1" price.
+type PriceTable map[string]ModelPrice
+
+var (
+	priceTableOnce sync.Once
+	priceTable     PriceTable
+)
+
+// LoadPriceTable returns the shared price table: the defaults, overlaid with
+// the JSON file named by LLM_PRICES_FILE, shaped like
+// {"gpt-4.1": {"input": 2.0, "output": 8.0}}
+func LoadPriceTable() PriceTable {
+	priceTableOnce.Do(func() {
+		priceTable = make(PriceTable, len(defaultModelPrices))
+		for model, price := range defaultModelPrices {
+			priceTable[model] = price
+		}
+		if path := os.Getenv("LLM_PRICES_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err == nil {
+				var extra map[string]ModelPrice
+				if err = json.Unmarshal(data, &extra); err == nil {
+					for model, price := range extra {
+						priceTable[strings.ToLower(model)] = price
+					}
+				}
+			}
+			if err != nil {
+				log.Printf("Could not load LLM prices from %s: %v", path, err)
+			}
+		}
+	})
+	return priceTable
+}
+
+// Cost returns the USD cost of usage on model, or 0 for unknown models
+func (t PriceTable) Cost(model string, usage LLMUsage) float64 {
+	model = strings.ToLower(model)
+	best := ""
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return 0
+	}
+	price := t[best]
+	return (float64(usage.PromptTokens)*price.InputPerMillion + float64(usage.CompletionTokens)*price.OutputPerMillion) / 1e6
+}
+
+// recordUsage sends one provider call to the router's recorder
+func (r *LLMRouter) recordUsage(ctx context.Context, task LLMTask, provider LLMProvider, req LLMRequest, resp *LLMResponse, callErr error, latency time.Duration) {
+	recorder := r.Recorder
+	if recorder == nil {
+		recorder = defaultUsageRecorder()
+	}
+	if recorder == nil {
+		return
+	}
+
+	scope := LLMUsageScopeFrom(ctx)
+	record := models.LLMUsageRecord{
+		Task:      string(task),
+		Provider:  provider.Name(),
+		Model:     req.Model,
+		LatencyMs: latency.Milliseconds(),
+		Success:   callErr == nil,
+		UserID:    scope.UserID,
+		HistoryID: scope.HistoryID,
+		RunID:     scope.RunID,
+		Endpoint:  scope.Endpoint,
+		CreatedAt: time.Now().UTC(),
+	}
+	if resp != nil {
+		if resp.Model != "" {
+			record.Model = resp.Model
+		}
+		record.PromptTokens = resp.Usage.PromptTokens
+		record.CompletionTokens = resp.Usage.CompletionTokens
+		record.TotalTokens = resp.Usage.TotalTokens
+		record.CostUSD = LoadPriceTable().Cost(record.Model, resp.Usage)
+	}
+	recorder.RecordLLMUsage(ctx, record)
+}
@@ -0,0 +1,272 @@
+package processors
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TextFormat selects how WebProcessor renders page content
+type TextFormat string
+
+const (
+	// TextFormatMarkdown keeps headings, lists, tables and link targets
+	TextFormatMarkdown TextFormat = "markdown"
+	// TextFormatFlat collapses the page to one sentence per line
+	TextFormatFlat TextFormat = "flat"
+)
+
+var (
+	markdownBlankLines  = regexp.MustCompile(`\n{3,}`)
+	markdownLineSpaces  = regexp.MustCompile(`[ \t]+`)
+	markdownLineTrailer = regexp.MustCompile(` +\n`)
+)
+
+// markdownWriter converts an HTML subtree to Markdown
+type markdownWriter struct {
+	sb   strings.Builder
+	base *url.URL
+}
+
+// htmlToMarkdown renders n as Markdown, resolving relative links against base
+func htmlToMarkdown(n *html.Node, base *url.URL) string {
+	m := &markdownWriter{base: base}
+	m.block(n, 0)
+	return m.sb.String()
+}
+
+// block writes n and its children; depth is the list nesting level
+func (m *markdownWriter) block(n *html.Node, depth int) {
+	if n.Type == html.TextNode {
+		m.sb.WriteString(markdownLineSpaces.ReplaceAllString(strings.ReplaceAll(n.Data, "\n", " "), " "))
+		return
+	}
+	if n.Type != html.ElementNode && n.Type != html.DocumentNode {
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "noscript", "template", "head":
+		return
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		if text := m.inline(n); text != "" {
+			m.paragraph(strings.Repeat("#", level) + " " + text)
+		}
+		return
+	case "p", "div", "section", "article", "main", "header", "footer", "aside", "blockquote", "address", "dd", "dt", "figure", "figcaption":
+		m.breakBlock()
+		m.children(n, depth)
+		m.breakBlock()
+		return
+	case "br":
+		m.sb.WriteString("\n")
+		return
+	case "hr":
+		m.paragraph("---")
+		return
+	case "ul", "ol":
+		m.list(n, depth)
+		return
+	case "table":
+		m.table(n)
+		return
+	case "pre":
+		m.paragraph("```\n" + strings.TrimRight(nodeText(n), "\n") + "\n```")
+		return
+	case "a", "strong", "b", "em", "i", "code":
+		m.sb.WriteString(m.inline(n))
+		return
+	}
+	m.children(n, depth)
+}
+
+func (m *markdownWriter) children(n *html.Node, depth int) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		m.block(c, depth)
+	}
+}
+
+// list writes ul/ol items, indenting nested lists
+func (m *markdownWriter) list(n *html.Node, depth int) {
+	if depth == 0 {
+		m.breakBlock()
+	}
+	index := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		marker := "-"
+		if n.Data == "ol" {
+			marker = fmt.Sprintf("%d.", index)
+			index++
+		}
+
+		item := &markdownWriter{base: m.base}
+		var nested []*html.Node
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.Data == "ul" || c.Data == "ol") {
+				nested = append(nested, c)
+				continue
+			}
+			item.block(c, depth+1)
+		}
+		text := strings.Join(strings.Fields(item.sb.String()), " ")
+		if text != "" {
+			m.sb.WriteString(strings.Repeat("  ", depth) + marker + " " + text + "\n")
+		}
+		for _, sub := range nested {
+			m.list(sub, depth+1)
+		}
+	}
+	if depth == 0 {
+		m.sb.WriteString("\n")
+	}
+}
+
+// table writes a pipe table, treating the first row as the header
+func (m *markdownWriter) table(n *html.Node) {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.Data == "tr" {
+			var cells []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+					cells = append(cells, strings.ReplaceAll(m.inline(cell), "|", "\\|"))
+				}
+			}
+			if len(cells) > 0 {
+				rows = append(rows, cells)
+			}
+			return
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return
+	}
+
+	// Single-column tables are layout, not data
+	if len(rows[0]) == 1 {
+		for _, row := range rows {
+			m.paragraph(row[0])
+		}
+		return
+	}
+
+	m.breakBlock()
+	width := len(rows[0])
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		m.sb.WriteString("| " + strings.Join(row[:width], " | ") + " |\n")
+		if i == 0 {
+			m.sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	m.sb.WriteString("\n")
+}
+
+// inline renders the text of n with emphasis, code and links on one line
+func (m *markdownWriter) inline(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(m.inline(c))
+	}
+	text := strings.Join(strings.Fields(sb.String()), " ")
+	if text == "" || n.Type != html.ElementNode {
+		return text
+	}
+
+	switch n.Data {
+	case "strong", "b":
+		return "**" + text + "**"
+	case "em", "i":
+		return "_" + text + "_"
+	case "code":
+		return "`" + text + "`"
+	case "br":
+		return " "
+	case "a":
+		href := strings.TrimSpace(getAttr(n, "href"))
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return text
+		}
+		if m.base != nil {
+			if resolved, err := m.base.Parse(href); err == nil {
+				href = resolved.String()
+			}
+		}
+		if href == text || "mailto:"+text == href {
+			return text
+		}
+		return "[" + text + "](" + href + ")"
+	}
+	return text
+}
+
+// paragraph writes text as its own block
+func (m *markdownWriter) paragraph(text string) {
+	m.breakBlock()
+	m.sb.WriteString(text)
+	m.sb.WriteString("\n\n")
+}
+
+// breakBlock ends the current line so the next block starts on its own
+func (m *markdownWriter) breakBlock() {
+	if s := m.sb.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+		if strings.HasSuffix(s, "\n") {
+			m.sb.WriteString("\n")
+		} else {
+			m.sb.WriteString("\n\n")
+		}
+	}
+}
+
+// cleanMarkdown removes junk phrases and normalises spacing without
+// disturbing line structure
+func (w *WebProcessor) cleanMarkdown(text string) string {
+	for _, phrase := range w.JunkPhrases {
+		text = strings.ReplaceAll(text, phrase, "")
+	}
+	text = strings.ReplaceAll(text, "\r", "")
+	text = strings.ReplaceAll(text, " ", " ")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		lines[i] = line[:indent] + markdownLineSpaces.ReplaceAllString(strings.TrimSpace(line), " ")
+	}
+	text = markdownLineTrailer.ReplaceAllString(strings.Join(lines, "\n"), "\n")
+	text = markdownBlankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// renderContent converts a content node to text in the processor's output format
+func (w *WebProcessor) renderContent(n *html.Node, pageURL string) string {
+	if w.Format == TextFormatFlat {
+		return w.cleanText(w.extractText(n))
+	}
+	base, _ := url.Parse(pageURL)
+	return w.cleanMarkdown(htmlToMarkdown(n, base))
+}
+
+// renderPlainText cleans text that has no HTML structure (PDF output,
+// structured-data descriptions) in the processor's output format
+func (w *WebProcessor) renderPlainText(text string) string {
+	if w.Format == TextFormatFlat {
+		return w.cleanText(text)
+	}
+	return w.cleanMarkdown(text)
+}
@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
-	"easy-apply/constants"
+	"easy-apply/prompts"
 
 	"github.com/joho/godotenv"
 	"github.com/openai/openai-go"
@@ -18,9 +19,6 @@ import (
 // Configuration constants
 const (
 	defaultTimeout = 100 * time.Second
-	maxRetries     = 3
-	retryDelay     = 500 * time.Millisecond
-	cacheTTL       = 5 * time.Minute
 )
 
 // Singleton client management
@@ -30,71 +28,25 @@ var (
 	clientErr  error
 )
 
-// CacheItem represents a cached value with expiration
-type CacheItem struct {
-	value      string
-	expiration time.Time
-}
-
-// IsExpired checks if the cache item has expired
-func (c CacheItem) IsExpired() bool {
-	return time.Now().After(c.expiration)
-}
-
-// Cache provides thread-safe caching functionality
-type Cache struct {
-	store sync.Map
-	ttl   time.Duration
-}
-
-// NewCache creates a new cache with the specified TTL
-func NewCache(ttl time.Duration) *Cache {
-	return &Cache{
-		ttl: ttl,
-	}
-}
-
-// Set stores a value in the cache with expiration
-func (c *Cache) Set(key, value string) {
-	item := CacheItem{
-		value:      value,
-		expiration: time.Now().Add(c.ttl),
-	}
-	c.store.Store(key, item)
-}
-
-// Get retrieves a value from the cache if it exists and hasn't expired
-func (c *Cache) Get(key string) (string, bool) {
-	val, exists := c.store.Load(key)
-	if !exists {
-		return "", false
-	}
-
-	item := val.(CacheItem)
-	if item.IsExpired() {
-		c.store.Delete(key)
-		return "", false
-	}
-
-	return item.value, true
-}
-
-// OpenAIProcessor handles OpenAI API interactions with caching and retry logic
+// OpenAIProcessor runs resume and cover letter generation, subject extraction and
+// recommendation analysis through the configured LLM providers. Replies are cached by the router.
 type OpenAIProcessor struct {
-	client openai.Client
-	cache  *Cache
+	llm *LLMRouter
 }
 
-// NewOpenAIProcessor creates a new OpenAI processor with singleton client
+// NewOpenAIProcessor creates a processor whose tasks are routed by NewLLMRouterFromEnv
 func NewOpenAIProcessor() (*OpenAIProcessor, error) {
-	if err := initializeClient(); err != nil {
-		return nil, fmt.Errorf("failed to initialize OpenAI client: %w", err)
+	llm, err := NewLLMRouterFromEnv(context.Background(), LLMTaskResumeGeneration, LLMTaskSubjectExtraction, LLMTaskRecommendation, LLMTaskCoverLetter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize LLM providers: %w", err)
 	}
+	return NewOpenAIProcessorWithRouter(llm), nil
+}
 
-	return &OpenAIProcessor{
-		client: client,
-		cache:  NewCache(cacheTTL),
-	}, nil
+// NewOpenAIProcessorWithRouter creates a processor over an existing router,
+// e.g. one backed by a FakeProvider
+func NewOpenAIProcessorWithRouter(llm *LLMRouter) *OpenAIProcessor {
+	return &OpenAIProcessor{llm: llm}
 }
 
 // initializeClient initializes the OpenAI client as a singleton
@@ -113,203 +65,135 @@ func initializeClient() error {
 		client = openai.NewClient(
 			option.WithAPIKey(apiKey),
 			option.WithRequestTimeout(defaultTimeout),
+			// Retries are left to the LLM router's policy
+			option.WithMaxRetries(0),
 		)
 	})
 
 	return clientErr
 }
 
-// ProcessDocuments processes documents through OpenAI with retry logic and caching
-func (p *OpenAIProcessor) ProcessDocuments(documents string) (string, error) {
-	if cached, found := p.cache.Get(documents); found {
-		return cached, nil
-	}
-
-	result, err := p.executeWithRetry(func() (string, error) {
-		return p.generateResumeAndCoverLetter(documents)
-	})
-
+// ProcessDocuments generates the tailored resume and cover letter JSON from
+// userMessage, a rendered prompts.ResumeUser. When onText is set, the reply is
+// streamed to it as it is generated; the returned JSON is the validated final
+// reply either way. The response's PromptVersion names both prompts used.
+func (p *OpenAIProcessor) ProcessDocuments(ctx context.Context, userMessage prompts.Rendered, onText func(text string)) (*LLMResponse, error) {
+	system, err := prompts.Render(prompts.ResumeSystem, promptSubject(ctx), nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	p.cache.Set(documents, result)
-	return result, nil
+	req := LLMRequest{
+		System:        system.Text,
+		Messages:      []LLMMessage{{Role: LLMRoleUser, Content: userMessage.Text}},
+		PromptVersion: PromptVersion(system, userMessage),
+	}
+	if onText != nil {
+		return p.llm.StreamJSON(ctx, LLMTaskResumeGeneration, req, onText)
+	}
+	return p.llm.CompleteJSON(ctx, LLMTaskResumeGeneration, req)
 }
 
-// GenerateSubjectName generates a brief subject name with retries and caching
-func (p *OpenAIProcessor) GenerateSubjectName(jobDescription string) (string, error) {
-	cacheKey := "subject:" + jobDescription
-
-	if cached, found := p.cache.Get(cacheKey); found {
-		return cached, nil
+// ReviseDocuments asks for the documents again without claims, following up
+// on previous, the reply ProcessDocuments returned for userMessage
+func (p *OpenAIProcessor) ReviseDocuments(ctx context.Context, userMessage prompts.Rendered, previous string, claims []string, onText func(text string)) (*LLMResponse, error) {
+	subject := promptSubject(ctx)
+	system, err := prompts.Render(prompts.ResumeSystem, subject, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	result, err := p.executeWithRetry(func() (string, error) {
-		return p.generateSubjectNameWithContext(jobDescription)
-	})
-
+	revision, err := prompts.Render(prompts.ResumeRevision, subject, prompts.Vars{"claims": claims})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	req := LLMRequest{
+		System: system.Text,
+		Messages: []LLMMessage{
+			{Role: LLMRoleUser, Content: userMessage.Text},
+			{Role: LLMRoleAssistant, Content: previous},
+			{Role: LLMRoleUser, Content: revision.Text},
+		},
+		PromptVersion: PromptVersion(system, userMessage, revision),
 	}
-
-	p.cache.Set(cacheKey, result)
-	return result, nil
-}
-
-// AnalyzeResumeForRecommendation analyzes a resume for job recommendations
-func (p *OpenAIProcessor) AnalyzeResumeForRecommendation(resume string) (string, error) {
-	prompt := fmt.Sprintf("**Resume:**{resume}\n%s", resume)
-
-	params := chatCompletionParams{
-		model:       constants.RECOMMENDATIONS_MODEL,
-		systemMsg:   constants.RECOMMENDATIONS_INSTRUCTION,
-		userMsg:     prompt,
-		temperature: 0.5,
-		maxTokens:   512,
-		topP:        1.0,
+	if onText != nil {
+		return p.llm.StreamJSON(ctx, LLMTaskResumeGeneration, req, onText)
 	}
-
-	return p.createChatCompletion(params)
-}
-
-// chatCompletionParams holds parameters for chat completion requests
-type chatCompletionParams struct {
-	model        openai.ChatModel
-	systemMsg    string
-	assistantMsg string
-	userMsg      string
-	temperature  float64
-	maxTokens    int
-	topP         float64
+	return p.llm.CompleteJSON(ctx, LLMTaskResumeGeneration, req)
 }
 
-// executeWithRetry executes a function with retry logic
-func (p *OpenAIProcessor) executeWithRetry(fn func() (string, error)) (string, error) {
-	var lastErr error
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			time.Sleep(retryDelay)
-		}
-
-		result, err := fn()
-		if err == nil {
-			return result, nil
-		}
-
-		lastErr = err
-		log.Printf("Attempt %d failed: %v", attempt+1, err)
+// GenerateCoverLetter writes a cover letter on its own from userMessage, a
+// rendered prompts.CoverLetterUser. When onText is set, the reply is streamed
+// to it as it is generated.
+func (p *OpenAIProcessor) GenerateCoverLetter(ctx context.Context, userMessage prompts.Rendered, onText func(text string)) (*LLMResponse, error) {
+	system, err := prompts.Render(prompts.CoverLetterSystem, promptSubject(ctx), nil)
+	if err != nil {
+		return nil, err
 	}
-
-	return "", fmt.Errorf("after %d attempts: %w", maxRetries, lastErr)
-}
-
-// generateResumeAndCoverLetter generates resume and cover letter content
-func (p *OpenAIProcessor) generateResumeAndCoverLetter(text string) (string, error) {
-	params := chatCompletionParams{
-		model:        constants.ResumeGenModel,
-		systemMsg:    constants.OpenAIInstruction,
-		userMsg:      text,
-		temperature:  0.3,
-		maxTokens:    8000,
-		topP:         1.0,
+	req := LLMRequest{
+		System:        system.Text,
+		Messages:      []LLMMessage{{Role: LLMRoleUser, Content: userMessage.Text}},
+		PromptVersion: PromptVersion(system, userMessage),
 	}
-
-	return p.createChatCompletion(params)
+	if onText != nil {
+		return p.llm.StreamJSON(ctx, LLMTaskCoverLetter, req, onText)
+	}
+	return p.llm.CompleteJSON(ctx, LLMTaskCoverLetter, req)
 }
 
-// generateSubjectNameWithContext generates a subject name based on job details
-func (p *OpenAIProcessor) generateSubjectNameWithContext(jobDetails string) (string, error) {
-	params := chatCompletionParams{
-		model:        constants.SubjectGenModel,
-		systemMsg:    constants.SubjectGenInstruction,
-		assistantMsg: constants.SubjectGenAssistantInstruction,
-		userMsg:      jobDetails,
-		temperature:  0.7,
-		maxTokens:    64,
-		topP:         0.9,
+// GenerateSubjectName extracts the job title and company as JSON
+func (p *OpenAIProcessor) GenerateSubjectName(ctx context.Context, jobDescription string) (*LLMResponse, error) {
+	subject := promptSubject(ctx)
+	system, err := prompts.Render(prompts.SubjectSystem, subject, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	result, err := p.createChatCompletion(params)
+	example, err := prompts.Render(prompts.SubjectExample, subject, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	log.Printf("\033[31mOpenAI response:\033[0m %s", result)
-	return result, nil
-}
-
-// createChatCompletion creates a chat completion request with the given parameters
-func (p *OpenAIProcessor) createChatCompletion(params chatCompletionParams) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
-	defer cancel()
-
-	messages := p.buildMessages(params)
-
-	chatCompletion, err := p.client.Chat.Completions.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Model:       params.model,
-			Messages:    messages,
-			Temperature: openai.Float(params.temperature),
-			MaxTokens:   openai.Int(int64(params.maxTokens)),
-			TopP:        openai.Float(params.topP),
+	resp, err := p.llm.CompleteJSON(ctx, LLMTaskSubjectExtraction, LLMRequest{
+		System: system.Text,
+		Messages: []LLMMessage{
+			{Role: LLMRoleAssistant, Content: example.Text},
+			{Role: LLMRoleUser, Content: jobDescription},
 		},
-	)
-
+		PromptVersion: PromptVersion(system, example),
+	})
 	if err != nil {
-		return "", fmt.Errorf("error creating chat completion: %w", err)
+		return nil, err
 	}
-
-	return p.extractContent(chatCompletion)
+	log.Printf("\033[31m%s response:\033[0m %s", resp.Provider, resp.Text)
+	return resp, nil
 }
 
-// buildMessages constructs the message array for chat completion
-func (p *OpenAIProcessor) buildMessages(params chatCompletionParams) []openai.ChatCompletionMessageParamUnion {
-	messages := []openai.ChatCompletionMessageParamUnion{
-		{
-			OfSystem: &openai.ChatCompletionSystemMessageParam{
-				Content: openai.ChatCompletionSystemMessageParamContentUnion{
-					OfString: openai.String(params.systemMsg),
-				},
-			},
-		},
-	}
-
-	// Add assistant message if provided
-	if params.assistantMsg != "" {
-		messages = append(messages, openai.ChatCompletionMessageParamUnion{
-			OfAssistant: &openai.ChatCompletionAssistantMessageParam{
-				Content: openai.ChatCompletionAssistantMessageParamContentUnion{
-					OfString: openai.String(params.assistantMsg),
-				},
-			},
-		})
+// AnalyzeResumeForRecommendation analyzes a resume for job recommendations
+func (p *OpenAIProcessor) AnalyzeResumeForRecommendation(ctx context.Context, resume string) (*LLMResponse, error) {
+	system, err := prompts.Render(prompts.RecommendationSystem, promptSubject(ctx), nil)
+	if err != nil {
+		return nil, err
 	}
-
-	// Add user message
-	messages = append(messages, openai.ChatCompletionMessageParamUnion{
-		OfUser: &openai.ChatCompletionUserMessageParam{
-			Content: openai.ChatCompletionUserMessageParamContentUnion{
-				OfString: openai.String(params.userMsg),
-			},
-		},
+	return p.llm.CompleteJSON(ctx, LLMTaskRecommendation, LLMRequest{
+		System:        system.Text,
+		Messages:      []LLMMessage{{Role: LLMRoleUser, Content: fmt.Sprintf("**Resume:**{resume}\n%s", resume)}},
+		PromptVersion: system.ID(),
 	})
-
-	return messages
 }
 
-// extractContent extracts content from chat completion response
-func (p *OpenAIProcessor) extractContent(chatCompletion *openai.ChatCompletion) (string, error) {
-	if len(chatCompletion.Choices) == 0 {
-		return "", fmt.Errorf("no response choices available")
+// PromptVersion joins the IDs of the prompts behind one request, e.g.
+// "resume_system@v1,resume_user@v1"
+func PromptVersion(rendered ...prompts.Rendered) string {
+	ids := make([]string, len(rendered))
+	for i, r := range rendered {
+		ids[i] = r.ID()
 	}
+	return strings.Join(ids, ",")
+}
 
-	content := chatCompletion.Choices[0].Message.Content
-	if content == "" {
-		return "", fmt.Errorf("empty response content")
+// promptSubject is the key prompt versions are A/B assigned by: the user, or
+// the scraper run for listings
+func promptSubject(ctx context.Context) string {
+	scope := LLMUsageScopeFrom(ctx)
+	if scope.UserID != "" {
+		return scope.UserID
 	}
-
-	return content, nil
+	return scope.RunID
 }
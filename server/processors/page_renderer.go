@@ -0,0 +1,183 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"easy-apply/retry"
+)
+
+// Rendering errors
+var (
+	ErrRenderFailed     = errors.New("page rendering failed")
+	ErrRenderNotAllowed = errors.New("page domain is not allowed to be rendered")
+)
+
+const (
+	defaultGotenbergHTMLURL = "http://gotenberg:3000/forms/chromium/convert/html"
+	defaultRenderTimeout    = 45 * time.Second
+	defaultRenderWaitDelay  = "2s"
+	maxRenderedPDFBytes     = 20 << 20 // 20MB
+)
+
+// PageRenderer renders JavaScript-driven pages to PDF with Gotenberg's
+// Chromium URL route, for job boards whose HTML is an empty shell.
+//
+// Chromium loads the page and everything it references itself, outside the
+// fetcher's address checks, so only pages on AllowedDomains are rendered.
+type PageRenderer struct {
+	Endpoint  string
+	Client    *http.Client
+	Timeout   time.Duration
+	WaitDelay string
+	// AllowedDomains lists the domains (and their subdomains) that may be rendered
+	AllowedDomains []string
+	// ForceDomains lists allowed domains that are always rendered,
+	// skipping the static-text check
+	ForceDomains []string
+}
+
+// NewPageRendererFromEnv configures a renderer from the environment, or
+// returns nil when rendering is off. It is opt-in: RENDER_ENABLED=true and
+// RENDER_ALLOWED_DOMAINS (comma-separated) are both required. Also read are
+// GOTENBERG_URL (its /convert/html route is swapped for /convert/url),
+// RENDER_TIMEOUT (a duration such as "45s") and RENDER_FORCE_DOMAINS
+// (comma-separated allowed domains that are always rendered).
+func NewPageRendererFromEnv() *PageRenderer {
+	if !strings.EqualFold(os.Getenv("RENDER_ENABLED"), "true") {
+		return nil
+	}
+	allowed := domainList(os.Getenv("RENDER_ALLOWED_DOMAINS"))
+	if len(allowed) == 0 {
+		log.Printf("RENDER_ENABLED is set without RENDER_ALLOWED_DOMAINS; page rendering is off")
+		return nil
+	}
+
+	endpoint := os.Getenv("GOTENBERG_URL")
+	if endpoint == "" {
+		endpoint = defaultGotenbergHTMLURL
+	}
+	endpoint = strings.Replace(endpoint, "/convert/html", "/convert/url", 1)
+
+	timeout := defaultRenderTimeout
+	if configured, err := time.ParseDuration(os.Getenv("RENDER_TIMEOUT")); err == nil && configured > 0 {
+		timeout = configured
+	}
+
+	return &PageRenderer{
+		Endpoint:       endpoint,
+		Client:         &http.Client{Timeout: timeout},
+		Timeout:        timeout,
+		WaitDelay:      defaultRenderWaitDelay,
+		AllowedDomains: allowed,
+		ForceDomains:   domainList(os.Getenv("RENDER_FORCE_DOMAINS")),
+	}
+}
+
+// domainList splits a comma-separated list of domains
+func domainList(value string) []string {
+	var domains []string
+	for _, domain := range strings.Split(value, ",") {
+		if domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), "."); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// Allowed reports whether pageURL is an http(s) URL on one of the allowed domains
+func (r *PageRenderer) Allowed(pageURL string) bool {
+	return r != nil && matchesDomain(pageURL, r.AllowedDomains)
+}
+
+// Forced reports whether pageURL's domain is allowed and configured to always be rendered
+func (r *PageRenderer) Forced(pageURL string) bool {
+	return r.Allowed(pageURL) && matchesDomain(pageURL, r.ForceDomains)
+}
+
+// matchesDomain reports whether pageURL's host is one of domains or a subdomain of one
+func matchesDomain(pageURL string, domains []string) bool {
+	parsed, err := url.Parse(pageURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderPDF asks Gotenberg to load pageURL in Chromium and returns the page as a PDF.
+// Only allowed domains are rendered, and the URL is also checked with ValidatePublicURL.
+func (r *PageRenderer) RenderPDF(ctx context.Context, pageURL string) ([]byte, error) {
+	if !r.Allowed(pageURL) {
+		return nil, fmt.Errorf("%w: %s", ErrRenderNotAllowed, pageURL)
+	}
+	if err := ValidatePublicURL(ctx, pageURL); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	fields := map[string]string{
+		"url":             pageURL,
+		"waitDelay":       r.WaitDelay,
+		"printBackground": "false",
+	}
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return nil, fmt.Errorf("failed to write form field %s: %w", key, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	resp, err := retry.Do(ctx, retry.Default, "Gotenberg render "+pageURL, func(attempt int) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Endpoint, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, retry.NewError(retry.ClassInvalidRequest, fmt.Errorf("failed to create render request: %w", err))
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		resp, err := r.Client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRenderFailed, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			return nil, retry.StatusError(resp, fmt.Errorf("%w: Gotenberg returned %d: %s", ErrRenderFailed, resp.StatusCode, strings.TrimSpace(string(detail))))
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	pdfBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxRenderedPDFBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading PDF: %v", ErrRenderFailed, err)
+	}
+	if len(pdfBytes) > maxRenderedPDFBytes {
+		return nil, fmt.Errorf("%w: rendered PDF exceeds %d bytes", ErrResponseTooLarge, maxRenderedPDFBytes)
+	}
+	return pdfBytes, nil
+}
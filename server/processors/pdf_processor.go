@@ -1,57 +1,146 @@
 package processors
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
+	"time"
+
+	"easy-apply/models"
+	"easy-apply/retry"
 
 	"github.com/ledongthuc/pdf"
 	docx "github.com/nguyenthenguyen/docx"
 )
 
 const (
-	ocrSpaceAPIURL  = "https://api.ocr.space/parse/image"
-	minTextLength   = 100 // Minimum characters to consider extraction successful
+	ocrSpaceAPIURL         = "https://api.ocr.space/parse/image"
+	ocrSpaceTimeout        = 2 * time.Minute  // all attempts at one OCR.Space request, capped by the caller's deadline
+	ocrSpaceRequestTimeout = 30 * time.Second // a single OCR.Space attempt
+	minTextLength          = 100 // Minimum characters to consider extraction successful
 )
 
+// ocrSpaceClient bounds each OCR.Space attempt on its own, so a stalled
+// connection cannot use up the whole retry budget
+var ocrSpaceClient = &http.Client{Timeout: ocrSpaceRequestTimeout}
+
 // FileProcessor handles various file types processing
-type FileProcessor struct{}
+type FileProcessor struct {
+	Limits ExtractionLimits
+	// DisableOCR skips the paid OCR.Space fallback for PDFs with little text
+	DisableOCR bool
+}
 
 // NewFileProcessor creates a new file processor
 func NewFileProcessor() *FileProcessor {
-	return &FileProcessor{}
+	return &FileProcessor{
+		Limits: DefaultExtractionLimits(),
+	}
 }
 
 // ProcessFileBuffer extracts text from a file buffer based on its type
-func (p *FileProcessor) ProcessFileBuffer(fileBuffer []byte, fileExt string) (string, error) {
+func (p *FileProcessor) ProcessFileBuffer(ctx context.Context, fileBuffer []byte, fileExt string) (string, models.ExtractionReport, error) {
+	return p.ProcessFileReader(ctx, bytes.NewReader(fileBuffer), int64(len(fileBuffer)), fileExt)
+}
+
+// ProcessFileReader extracts text from an in-memory document within the processor's
+// limits and reports on the quality of the extraction. The extraction, including
+// any OCR fallback, stops when ctx is cancelled or the time limit passes.
+func (p *FileProcessor) ProcessFileReader(ctx context.Context, r io.ReaderAt, size int64, fileExt string) (string, models.ExtractionReport, error) {
+	type extraction struct {
+		text   string
+		report models.ExtractionReport
+		err    error
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.Limits.Timeout)
+	defer cancel()
+	done := make(chan extraction, 1)
+
+	go func() {
+		defer func() {
+			// The PDF library reports malformed input by panicking
+			if rec := recover(); rec != nil {
+				done <- extraction{err: fmt.Errorf("%w: %v", ErrMalformedDocument, rec)}
+			}
+		}()
+		report := models.ExtractionReport{Format: strings.TrimPrefix(strings.ToLower(fileExt), ".")}
+		text, err := p.extract(ctx, r, size, fileExt, &report)
+		if err == nil {
+			completeExtractionReport(text, &report)
+		}
+		done <- extraction{text: text, report: report, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.text, res.report, res.err
+	case <-ctx.Done():
+		return "", models.ExtractionReport{}, extractionContextError(ctx, p.Limits.Timeout)
+	}
+}
+
+// extractionContextError reports why ctx ended: a passed deadline is an
+// extraction timeout, anything else (such as the client going away) is returned as is
+func extractionContextError(ctx context.Context, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v", ErrExtractionTimeout, timeout)
+	}
+	return ctx.Err()
+}
+
+// extract dispatches to the extractor for the given extension
+func (p *FileProcessor) extract(ctx context.Context, r io.ReaderAt, size int64, fileExt string, report *models.ExtractionReport) (string, error) {
 	switch strings.ToLower(fileExt) {
 	case ".pdf":
-		return p.processPDFBuffer(fileBuffer)
+		return p.processPDF(ctx, r, size, report)
 	case ".docx":
-		return p.processDOCXBuffer(fileBuffer)
+		return p.processDOCX(r, size, report)
 	case ".txt":
-		return string(fileBuffer), nil
+		if size > p.Limits.MaxDecompressedBytes {
+			return "", fmt.Errorf("%w: %d bytes", ErrDecompressedSizeExceeded, size)
+		}
+		buf := make([]byte, size)
+		if _, err := r.ReadAt(buf, 0); err != nil && err != io.EOF {
+			return "", fmt.Errorf("error reading text file: %v", err)
+		}
+		return string(buf), nil
 	default:
-		return "", fmt.Errorf("unsupported file format: %s", fileExt)
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, fileExt)
 	}
 }
 
-// processPDFBuffer handles PDF files with standard extraction and OCR fallback
-func (p *FileProcessor) processPDFBuffer(pdfBuffer []byte) (string, error) {
+// processPDF handles PDF files with standard extraction and OCR fallback
+func (p *FileProcessor) processPDF(ctx context.Context, r io.ReaderAt, size int64, report *models.ExtractionReport) (string, error) {
 	// First try standard extraction
-	text, err := p.extractTextFromPDFBuffer(pdfBuffer)
+	text, pages, err := p.extractTextFromPDF(ctx, r, size)
+	report.Pages = pages
 	if err != nil {
-		return "", fmt.Errorf("PDF extraction failed: %v", err)
+		return "", fmt.Errorf("PDF extraction failed: %w", err)
 	}
 
 	// If text is too short, try OCR
-	if len(text) < minTextLength {
-		ocrText, ocrErr := p.extractTextWithOCRSpace(pdfBuffer)
+	if len(text) < minTextLength && !p.DisableOCR {
+		// Don't start a paid OCR call for a caller that has stopped waiting
+		if ctx.Err() != nil {
+			return "", extractionContextError(ctx, p.Limits.Timeout)
+		}
+		pdfBuffer := make([]byte, size)
+		if _, err := r.ReadAt(pdfBuffer, 0); err != nil && err != io.EOF {
+			return text, fmt.Errorf("error reading PDF for OCR: %v", err)
+		}
+		report.OCRUsed = true
+		ocrText, ocrErr := p.extractTextWithOCRSpace(ctx, pdfBuffer, "document.pdf")
 		if ocrErr != nil {
 			return text, fmt.Errorf("standard extraction returned minimal text, OCR also failed: %v", ocrErr)
 		}
@@ -61,75 +150,148 @@ func (p *FileProcessor) processPDFBuffer(pdfBuffer []byte) (string, error) {
 	return text, nil
 }
 
-// extractTextFromPDFBuffer extracts text from PDF buffer using standard method
-func (p *FileProcessor) extractTextFromPDFBuffer(pdfBuffer []byte) (string, error) {
-	tmpFile, err := os.CreateTemp("", "pdf-*.pdf")
+// extractTextFromPDF extracts text from an in-memory PDF using the standard
+// method and returns the page count alongside the text
+func (p *FileProcessor) extractTextFromPDF(ctx context.Context, f io.ReaderAt, size int64) (string, int, error) {
+	r, err := pdf.NewReader(f, size)
 	if err != nil {
-		return "", fmt.Errorf("error creating temp file: %v", err)
-	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.Write(pdfBuffer); err != nil {
-		return "", fmt.Errorf("error writing to temp file: %v", err)
+		if errors.Is(err, pdf.ErrInvalidPassword) || strings.Contains(strings.ToLower(err.Error()), "encrypt") {
+			return "", 0, fmt.Errorf("%w: %v", ErrEncryptedDocument, err)
+		}
+		return "", 0, fmt.Errorf("%w: error opening PDF: %v", ErrMalformedDocument, err)
 	}
 
-	f, r, err := pdf.Open(tmpFile.Name())
-	if err != nil {
-		return "", fmt.Errorf("error opening PDF: %v", err)
+	totalPages := r.NumPage()
+	if totalPages > p.Limits.MaxPages {
+		return "", totalPages, fmt.Errorf("%w: %d pages (limit %d)", ErrTooManyPages, totalPages, p.Limits.MaxPages)
 	}
-	defer f.Close()
 
 	var textBuilder strings.Builder
-	totalPages := r.NumPage()
 
 	for pageIndex := 1; pageIndex <= totalPages; pageIndex++ {
-		p := r.Page(pageIndex)
-		if p.V.IsNull() {
+		if ctx.Err() != nil {
+			return "", totalPages, fmt.Errorf("stopped at page %d: %w", pageIndex, extractionContextError(ctx, p.Limits.Timeout))
+		}
+
+		page := r.Page(pageIndex)
+		if page.V.IsNull() {
 			continue
 		}
 
-		text, err := p.GetPlainText(nil)
+		text, err := page.GetPlainText(nil)
 		if err != nil {
-			return "", fmt.Errorf("error extracting text from page %d: %v", pageIndex, err)
+			return "", totalPages, fmt.Errorf("%w: error extracting text from page %d: %v", ErrMalformedDocument, pageIndex, err)
 		}
 
 		textBuilder.WriteString(fmt.Sprintf("--- Page %d ---\n", pageIndex))
 		textBuilder.WriteString(text)
 		textBuilder.WriteString("\n\n")
+
+		// Checked per page so a few huge pages are rejected before the rest are read
+		if int64(textBuilder.Len()) > p.Limits.MaxDecompressedBytes {
+			return "", totalPages, fmt.Errorf("%w: more than %d bytes of text by page %d", ErrDecompressedSizeExceeded, p.Limits.MaxDecompressedBytes, pageIndex)
+		}
 	}
 
-	return textBuilder.String(), nil
+	return textBuilder.String(), totalPages, nil
 }
 
-// processDOCXBuffer handles DOCX files
-func (p *FileProcessor) processDOCXBuffer(docxBuffer []byte) (string, error) {
-	tmpFile, err := os.CreateTemp("", "docx-*.docx")
-	if err != nil {
-		return "", fmt.Errorf("error creating temp file: %v", err)
+// processDOCX handles DOCX files after checking the archive against the limits
+func (p *FileProcessor) processDOCX(r io.ReaderAt, size int64, report *models.ExtractionReport) (string, error) {
+	if err := p.checkDOCXArchive(r, size); err != nil {
+		return "", err
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
 
-	if _, err := tmpFile.Write(docxBuffer); err != nil {
-		return "", fmt.Errorf("error writing to temp file: %v", err)
+	doc, err := docx.ReadDocxFromMemory(r, size)
+	if err != nil {
+		return "", fmt.Errorf("%w: error reading DOCX file: %v", ErrMalformedDocument, err)
 	}
+	defer doc.Close()
+
+	content := doc.Editable().GetContent()
+	report.Pages = 1 + strings.Count(content, `w:type="page"`)
+	return docxPlainText(content), nil
+}
+
+var (
+	docxParagraphEnd = regexp.MustCompile(`</w:p>|<w:br[^>]*/>|<w:tab[^>]*/>`)
+	xmlTag           = regexp.MustCompile(`<[^>]+>`)
+)
 
-	doc, err := docx.ReadDocxFile(tmpFile.Name())
+// docxPlainText converts WordprocessingML document XML to plain text,
+// keeping one line per paragraph
+func docxPlainText(content string) string {
+	text := docxParagraphEnd.ReplaceAllStringFunc(content, func(tag string) string {
+		if strings.HasPrefix(tag, "<w:tab") {
+			return "\t"
+		}
+		return "\n"
+	})
+	text = xmlTag.ReplaceAllString(text, "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+// checkDOCXArchive guards against zip bombs by bounding entry count and the
+// actual (not declared) uncompressed size of every entry
+func (p *FileProcessor) checkDOCXArchive(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
 	if err != nil {
-		return "", fmt.Errorf("error reading DOCX file: %v", err)
+		return fmt.Errorf("%w: not a valid DOCX archive: %v", ErrMalformedDocument, err)
 	}
-	defer doc.Close()
 
-	return doc.Editable().GetContent(), nil
+	if len(zr.File) == 0 {
+		return fmt.Errorf("%w: empty DOCX archive", ErrMalformedDocument)
+	}
+	if len(zr.File) > p.Limits.MaxArchiveEntries {
+		return fmt.Errorf("%w: %d archive entries (limit %d)", ErrDecompressedSizeExceeded, len(zr.File), p.Limits.MaxArchiveEntries)
+	}
+
+	remaining := p.Limits.MaxDecompressedBytes
+	for _, zf := range zr.File {
+		if zf.Flags&0x1 != 0 {
+			return fmt.Errorf("%w: archive entry %s is encrypted", ErrEncryptedDocument, zf.Name)
+		}
+		if zf.UncompressedSize64 > uint64(remaining) {
+			return fmt.Errorf("%w: archive expands beyond %d bytes", ErrDecompressedSizeExceeded, p.Limits.MaxDecompressedBytes)
+		}
+
+		rc, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("%w: error opening archive entry %s: %v", ErrMalformedDocument, zf.Name, err)
+		}
+		n, err := io.CopyN(io.Discard, rc, remaining+1)
+		rc.Close()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("%w: error reading archive entry %s: %v", ErrMalformedDocument, zf.Name, err)
+		}
+		remaining -= n
+		if remaining < 0 {
+			return fmt.Errorf("%w: archive expands beyond %d bytes", ErrDecompressedSizeExceeded, p.Limits.MaxDecompressedBytes)
+		}
+	}
+
+	return nil
 }
 
-// extractTextWithOCRSpace uses OCR.Space API for image-based content
-func (p *FileProcessor) extractTextWithOCRSpace(fileBuffer []byte) (string, error) {
+// ProcessImageBuffer extracts text from an image (such as a scanned advert) using
+// OCR, within the same time limit as a document
+func (p *FileProcessor) ProcessImageBuffer(ctx context.Context, imageBuffer []byte, fileExt string) (string, error) {
+	if int64(len(imageBuffer)) > p.Limits.MaxDecompressedBytes {
+		return "", fmt.Errorf("%w: %d bytes", ErrDecompressedSizeExceeded, len(imageBuffer))
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Limits.Timeout)
+	defer cancel()
+	return p.extractTextWithOCRSpace(ctx, imageBuffer, "advert"+strings.ToLower(fileExt))
+}
+
+// extractTextWithOCRSpace uses OCR.Space API for image-based content. The
+// filename's extension tells the API how to decode the file. Retries stop at
+// ctx's deadline, which callers tie to the extraction time limit.
+func (p *FileProcessor) extractTextWithOCRSpace(ctx context.Context, fileBuffer []byte, filename string) (string, error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 	
-	part, err := writer.CreateFormFile("file", "document.pdf")
+	part, err := writer.CreateFormFile("file", filename)
 	if err != nil {
 		return "", fmt.Errorf("failed to create form file: %v", err)
 	}
@@ -139,24 +301,31 @@ func (p *FileProcessor) extractTextWithOCRSpace(fileBuffer []byte) (string, erro
 	}
 	writer.Close()
 
-	req, err := http.NewRequest("POST", ocrSpaceAPIURL, body)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("apikey", os.Getenv("OCRSPACE_API_KEY"))
+	ctx, cancel := context.WithTimeout(ctx, ocrSpaceTimeout)
+	defer cancel()
+	resp, err := retry.Do(ctx, retry.Default, "OCR.Space "+filename, func(attempt int) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", ocrSpaceAPIURL, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, retry.NewError(retry.ClassInvalidRequest, fmt.Errorf("failed to create request: %v", err))
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("apikey", os.Getenv("OCRSPACE_API_KEY"))
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+		resp, err := ocrSpaceClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("API request failed: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, retry.StatusError(resp, fmt.Errorf("API returned non-200 status: %d", resp.StatusCode))
+		}
+		return resp, nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("API request failed: %v", err)
+		return "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
-	}
-
 	var result struct {
 		ParsedResults []struct {
 			ParsedText string `json:"ParsedText"`
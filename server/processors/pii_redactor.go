@@ -0,0 +1,217 @@
+package processors
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"easy-apply/models"
+)
+
+// PII categories recognised by PIIRedactor
+const (
+	PIITypeEmail      = "EMAIL"
+	PIITypePhone      = "PHONE"
+	PIITypeNationalID = "NATIONAL_ID"
+	PIITypeAddress    = "ADDRESS"
+)
+
+// piiPattern describes how to find one category of PII. If group is non-zero
+// only that capture group is redacted, so labels such as "Address:" survive.
+type piiPattern struct {
+	piiType string
+	re      *regexp.Regexp
+	group   int
+	valid   func(string) bool
+}
+
+var piiPatterns = []piiPattern{
+	{
+		piiType: PIITypeEmail,
+		re:      regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		piiType: PIITypeNationalID,
+		re:      regexp.MustCompile(`(?i)\b(?:national\s+id(?:\s+(?:no\.?|number))?|id\s+(?:no\.?|number)|passport(?:\s+(?:no\.?|number))?|nin)\s*[:#.\-]?\s*([A-Z0-9][A-Z0-9\-/]{5,19})`),
+		group:   1,
+	},
+	{
+		piiType: PIITypeAddress,
+		re:      regexp.MustCompile(`(?im)\b(?:home\s+address|residential\s+address|physical\s+address|postal\s+address|address|residence)\s*[:\-]\s*([^\n<]{4,120})`),
+		group:   1,
+	},
+	{
+		piiType: PIITypeAddress,
+		re:      regexp.MustCompile(`(?i)\bP\.?\s?O\.?\s+Box\s+\d+[^\n<]{0,60}`),
+	},
+	{
+		piiType: PIITypePhone,
+		re:      regexp.MustCompile(`\+?\(?\d[\d\s\-().]{7,18}\d`),
+		valid: func(s string) bool {
+			digits := 0
+			for _, r := range s {
+				if r >= '0' && r <= '9' {
+					digits++
+				}
+			}
+			// Years ("2018 - 2021") and short numbers are not phone numbers
+			return digits >= 9 && digits <= 15 && !yearRangePattern.MatchString(s)
+		},
+	},
+}
+
+var yearRangePattern = regexp.MustCompile(`^\(?(19|20)\d{2}\)?\s*[-–]\s*\(?(19|20)\d{2}\)?$`)
+
+// PIIRedactor swaps PII for stable placeholders before text leaves the service
+type PIIRedactor struct {
+	Enabled bool
+	Types   map[string]bool
+	// AuditKey keys the HMAC of each redacted value in the audit. Without a
+	// key the audit records no value hashes at all, since a plain hash of a
+	// phone number or ID can be brute-forced.
+	AuditKey []byte
+}
+
+// NewPIIRedactor creates a redactor for the given PII types
+func NewPIIRedactor(enabled bool, types ...string) *PIIRedactor {
+	r := &PIIRedactor{
+		Enabled: enabled,
+		Types:   make(map[string]bool, len(types)),
+	}
+	for _, t := range types {
+		r.Types[strings.ToUpper(strings.TrimSpace(t))] = true
+	}
+	return r
+}
+
+// NewPIIRedactorFromEnv configures a redactor from PII_REDACTION_ENABLED
+// (default true), PII_REDACTION_TYPES (default: all types) and
+// PII_AUDIT_HMAC_KEY (default: no value hashes in the audit)
+func NewPIIRedactorFromEnv() *PIIRedactor {
+	enabled := !strings.EqualFold(os.Getenv("PII_REDACTION_ENABLED"), "false")
+
+	types := []string{PIITypeEmail, PIITypePhone, PIITypeNationalID, PIITypeAddress}
+	if configured := os.Getenv("PII_REDACTION_TYPES"); configured != "" {
+		types = strings.Split(configured, ",")
+	}
+
+	r := NewPIIRedactor(enabled, types...)
+	if key := os.Getenv("PII_AUDIT_HMAC_KEY"); key != "" {
+		r.AuditKey = []byte(key)
+	}
+	return r
+}
+
+// Redaction holds the placeholder mapping for one redacted document
+type Redaction struct {
+	values      map[string]string // placeholder -> original value
+	occurrences map[string]int
+	types       map[string]string // placeholder -> PII type
+	auditKey    []byte
+}
+
+// Redact replaces detected PII with placeholders such as [EMAIL_1]. The same
+// value always maps to the same placeholder within one Redaction.
+func (r *PIIRedactor) Redact(text string) (string, *Redaction) {
+	redaction := &Redaction{
+		values:      make(map[string]string),
+		occurrences: make(map[string]int),
+		types:       make(map[string]string),
+	}
+	if r != nil {
+		redaction.auditKey = r.AuditKey
+	}
+	if r == nil || !r.Enabled {
+		return text, redaction
+	}
+
+	byValue := make(map[string]string)
+	counters := make(map[string]int)
+
+	for _, pattern := range piiPatterns {
+		if !r.Types[pattern.piiType] {
+			continue
+		}
+
+		type span struct {
+			start, end  int
+			placeholder string
+		}
+		var spans []span
+
+		// Assign placeholders in reading order, then replace from the end so
+		// earlier indexes stay valid
+		for _, match := range pattern.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := match[2*pattern.group], match[2*pattern.group+1]
+			if start < 0 {
+				continue
+			}
+			value := strings.TrimSpace(text[start:end])
+			if value == "" || strings.HasPrefix(value, "[") || (pattern.valid != nil && !pattern.valid(value)) {
+				continue
+			}
+
+			placeholder, seen := byValue[value]
+			if !seen {
+				counters[pattern.piiType]++
+				placeholder = fmt.Sprintf("[%s_%d]", pattern.piiType, counters[pattern.piiType])
+				byValue[value] = placeholder
+				redaction.values[placeholder] = value
+				redaction.types[placeholder] = pattern.piiType
+			}
+			redaction.occurrences[placeholder]++
+
+			valueStart := start + strings.Index(text[start:end], value)
+			spans = append(spans, span{start: valueStart, end: valueStart + len(value), placeholder: placeholder})
+		}
+
+		for i := len(spans) - 1; i >= 0; i-- {
+			text = text[:spans[i].start] + spans[i].placeholder + text[spans[i].end:]
+		}
+	}
+
+	return text, redaction
+}
+
+// Restore puts the original values back in place of their placeholders
+func (rd *Redaction) Restore(text string) string {
+	if rd == nil || len(rd.values) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(rd.values))
+	for placeholder, value := range rd.values {
+		pairs = append(pairs, placeholder, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Audit summarises the redaction without exposing any original values. Value
+// hashes are keyed HMACs and are left out when the redactor has no AuditKey.
+func (rd *Redaction) Audit(enabled bool) models.RedactionAudit {
+	audit := models.RedactionAudit{Enabled: enabled, Entries: []models.RedactionEntry{}}
+	if rd == nil {
+		return audit
+	}
+	for placeholder, value := range rd.values {
+		entry := models.RedactionEntry{
+			Type:        rd.types[placeholder],
+			Placeholder: placeholder,
+			Occurrences: rd.occurrences[placeholder],
+		}
+		if len(rd.auditKey) > 0 {
+			mac := hmac.New(sha256.New, rd.auditKey)
+			mac.Write([]byte(value))
+			entry.ValueHash = hex.EncodeToString(mac.Sum(nil))[:16]
+		}
+		audit.Entries = append(audit.Entries, entry)
+	}
+	sort.Slice(audit.Entries, func(i, j int) bool {
+		return audit.Entries[i].Placeholder < audit.Entries[j].Placeholder
+	})
+	return audit
+}
@@ -0,0 +1,224 @@
+package processors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	robotsCacheTTL = time.Hour
+	// robotsCacheMaxHosts bounds the origins kept, since they come from user-submitted links
+	robotsCacheMaxHosts = 1000
+)
+
+// robotsRules holds the Allow/Disallow path prefixes that apply to our user agent
+type robotsRules struct {
+	allow     []string
+	disallow  []string
+	fetchedAt time.Time
+}
+
+// robotsCache keeps parsed robots.txt rules per scheme and host for
+// robotsCacheTTL, evicting the least recently used origins beyond robotsCacheMaxHosts
+type robotsCache struct {
+	mu    sync.Mutex
+	index *lruIndex
+	hosts map[string]*robotsRules
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{index: newLRUIndex(robotsCacheMaxHosts), hosts: make(map[string]*robotsRules)}
+}
+
+// get returns the unexpired rules for origin, if any
+func (c *robotsCache) get(origin string) (*robotsRules, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.index.get(origin)
+	if entry == nil {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		c.index.remove(origin)
+		delete(c.hosts, origin)
+		return nil, false
+	}
+	return c.hosts[origin], true
+}
+
+// put stores rules for origin, dropping expired and least recently used origins
+func (c *robotsCache) put(origin string, rules *robotsRules) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range c.index.removeExpired(time.Now()) {
+		delete(c.hosts, key)
+	}
+	c.hosts[origin] = rules
+	for _, key := range c.index.put(&lruEntry{key: origin, expiresAt: rules.fetchedAt.Add(robotsCacheTTL)}) {
+		delete(c.hosts, key)
+	}
+}
+
+// checkRobots returns ErrRobotsDisallowed when RespectRobots is set and the
+// site's robots.txt forbids the URL. Missing or unreadable robots.txt allows everything.
+func (f *Fetcher) checkRobots(ctx context.Context, rawURL string) error {
+	if !f.RespectRobots {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	if f.robots == nil {
+		f.robots = newRobotsCache()
+	}
+
+	origin := parsed.Scheme + "://" + parsed.Host
+	rules, ok := f.robots.get(origin)
+	if !ok {
+		rules = f.fetchRobots(ctx, origin)
+		f.robots.put(origin, rules)
+	}
+
+	path := parsed.EscapedPath()
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+	if !rules.allowed(path) {
+		return fmt.Errorf("%w: %s", ErrRobotsDisallowed, rawURL)
+	}
+	return nil
+}
+
+// fetchRobots downloads and parses robots.txt for an origin
+func (f *Fetcher) fetchRobots(ctx context.Context, origin string) *robotsRules {
+	rules := &robotsRules{fetchedAt: time.Now()}
+
+	resp, err := f.do(ctx, http.MethodGet, origin+"/robots.txt", f.Client, nil)
+	if err != nil {
+		return rules
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return rules
+	}
+	body, err := f.readBody(resp)
+	if err != nil {
+		return rules
+	}
+
+	agent := strings.ToLower(strings.SplitN(f.UserAgent, "/", 2)[0])
+	parseRobots(body, agent, rules)
+	return rules
+}
+
+// parseRobots fills rules from the group naming agent, or the "*" group if there is none
+func parseRobots(body []byte, agent string, rules *robotsRules) {
+	var (
+		specific, wildcard robotsRules
+		groupAgents        []string
+		inRules            bool
+		sawSpecific        bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			for _, a := range groupAgents {
+				var target *robotsRules
+				switch {
+				case a == "*":
+					target = &wildcard
+				case a != "" && strings.Contains(agent, a):
+					target = &specific
+					sawSpecific = true
+				default:
+					continue
+				}
+				if key == "allow" {
+					target.allow = append(target.allow, value)
+				} else {
+					target.disallow = append(target.disallow, value)
+				}
+			}
+		}
+	}
+
+	chosen := wildcard
+	if sawSpecific {
+		chosen = specific
+	}
+	rules.allow = chosen.allow
+	rules.disallow = chosen.disallow
+}
+
+// allowed applies the longest matching rule; Allow wins ties
+func (r *robotsRules) allowed(path string) bool {
+	longestAllow, longestDisallow := -1, -1
+	for _, prefix := range r.allow {
+		if robotsMatch(prefix, path) && len(prefix) > longestAllow {
+			longestAllow = len(prefix)
+		}
+	}
+	for _, prefix := range r.disallow {
+		if robotsMatch(prefix, path) && len(prefix) > longestDisallow {
+			longestDisallow = len(prefix)
+		}
+	}
+	return longestDisallow < 0 || longestAllow >= longestDisallow
+}
+
+// robotsMatch matches a robots.txt path pattern supporting the * and $ wildcards
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if !anchored {
+		return true
+	}
+	if len(parts) == 1 {
+		return rest == ""
+	}
+	return strings.HasSuffix(path, parts[len(parts)-1])
+}
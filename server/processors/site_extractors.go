@@ -0,0 +1,221 @@
+package processors
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"easy-apply/models"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// SiteExtractor holds CSS-selector rules for pulling a job posting out of a
+// known job board. Each field lists selectors in priority order; the first
+// one that matches non-empty text wins.
+type SiteExtractor struct {
+	Name        string
+	Title       []string
+	Company     []string
+	Deadline    []string
+	Description []string
+	// Remove lists elements dropped from the description before its text is read
+	Remove []string
+}
+
+// SiteExtractors maps domain substrings to per-site extraction rules, keyed
+// the same way as utils.SupportedJobSites
+var SiteExtractors = map[string]SiteExtractor{
+	// WP Job Manager boards
+	"careersmw.com": {
+		Name:        "Careers MW",
+		Title:       []string{".single_job_listing .entry-title", "h1.entry-title", "h1"},
+		Company:     []string{".company .name strong", ".company strong", ".company-name"},
+		Deadline:    []string{".application-deadline", ".job-listing-meta .deadline"},
+		Description: []string{".job_description", ".single_job_listing"},
+		Remove:      []string{".job_application", ".job-manager-related-jobs", ".sharedaddy", ".jp-relatedposts"},
+	},
+	"jobsearchmalawi.com": {
+		Name:        "JobSearch Malawi",
+		Title:       []string{".single_job_listing .entry-title", "h1.entry-title", ".page-title", "h1"},
+		Company:     []string{".company .name strong", ".company strong", ".company-name"},
+		Deadline:    []string{".application-deadline", "li.application-deadline"},
+		Description: []string{".job_description", ".single_job_listing"},
+		Remove:      []string{".job_application", ".related_jobs", ".sharedaddy", ".jp-relatedposts"},
+	},
+	"ntchito.com": {
+		Name:        "Ntchito",
+		Title:       []string{"h1.entry-title", "h1"},
+		Company:     []string{".company-name", ".company strong"},
+		Deadline:    []string{".application-deadline"},
+		Description: []string{".job_description", ".entry-content"},
+		Remove:      []string{".job_application", ".sharedaddy", ".jp-relatedposts"},
+	},
+	"jobs.unicef.org": {
+		Name:        "UNICEF",
+		Title:       []string{"#job-details h1", "h1"},
+		Company:     nil,
+		Deadline:    []string{".close-date time", ".close-date"},
+		Description: []string{"#job-details"},
+		Remove:      []string{".apply-button", ".share-buttons"},
+	},
+}
+
+// baseRemoveSelectors are dropped from every description before its text is read
+var baseRemoveSelectors = []string{"script", "style", "noscript"}
+
+// compiledSelectors holds every selector used by SiteExtractors, compiled once at init
+var compiledSelectors = make(map[string]cascadia.Sel)
+
+func init() {
+	for _, extractor := range SiteExtractors {
+		for _, list := range [][]string{baseRemoveSelectors, extractor.Title, extractor.Company, extractor.Deadline, extractor.Description, extractor.Remove} {
+			for _, sel := range list {
+				if _, ok := compiledSelectors[sel]; ok {
+					continue
+				}
+				matcher, err := cascadia.Parse(sel)
+				if err != nil {
+					log.Printf("Invalid selector %q for %s: %v", sel, extractor.Name, err)
+					continue
+				}
+				compiledSelectors[sel] = matcher
+			}
+		}
+	}
+}
+
+// FindSiteExtractor returns the extraction rules registered for a URL's host
+func FindSiteExtractor(pageURL string) (SiteExtractor, bool) {
+	parsed, err := url.Parse(pageURL)
+	if err != nil {
+		return SiteExtractor{}, false
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	for domainKey, extractor := range SiteExtractors {
+		if strings.Contains(host, domainKey) {
+			return extractor, true
+		}
+	}
+	return SiteExtractor{}, false
+}
+
+// Extract applies the site rules to a parsed page. ok is false when no
+// description was found, in which case callers should use the generic heuristic.
+func (e SiteExtractor) Extract(doc *html.Node) (fields models.JobPostingFields, ok bool) {
+	descNode := e.first(doc, e.Description)
+	if descNode == nil {
+		return models.JobPostingFields{}, false
+	}
+
+	for _, sel := range append(append([]string(nil), baseRemoveSelectors...), e.Remove...) {
+		for _, n := range e.queryAll(descNode, sel) {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+		}
+	}
+
+	fields = models.JobPostingFields{
+		Title:            e.firstText(doc, e.Title),
+		Company:          e.firstText(doc, e.Company),
+		Deadline:         e.firstText(doc, e.Deadline),
+		Description:      strings.TrimSpace(nodeText(descNode)),
+		ExtractionMethod: e.Name,
+	}
+	if fields.Description == "" {
+		return models.JobPostingFields{}, false
+	}
+	return fields, true
+}
+
+// ContentNode returns the element the description is read from, or nil
+func (e SiteExtractor) ContentNode(doc *html.Node) *html.Node {
+	return e.first(doc, e.Description)
+}
+
+// jobPostingText renders known fields as a header block followed by body
+func jobPostingText(f models.JobPostingFields, body string) string {
+	var sb strings.Builder
+	for _, line := range []struct{ label, value string }{
+		{"Job Title", f.Title},
+		{"Company", f.Company},
+		{"Location", f.Location},
+		{"Employment Type", f.EmploymentType},
+		{"Date Posted", f.DatePosted},
+		{"Application Deadline", f.Deadline},
+	} {
+		if line.value != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", line.label, line.value)
+		}
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(body)
+	return sb.String()
+}
+
+// first returns the first node matched by the highest-priority selector
+func (e SiteExtractor) first(doc *html.Node, selectors []string) *html.Node {
+	for _, sel := range selectors {
+		matcher, ok := e.selector(sel)
+		if !ok {
+			continue
+		}
+		if n := cascadia.Query(doc, matcher); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// firstText returns the collapsed text of the first selector with non-empty content
+func (e SiteExtractor) firstText(doc *html.Node, selectors []string) string {
+	for _, sel := range selectors {
+		if n := e.first(doc, []string{sel}); n != nil {
+			if text := strings.Join(strings.Fields(nodeText(n)), " "); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// queryAll returns every node under root matching sel
+func (e SiteExtractor) queryAll(root *html.Node, sel string) []*html.Node {
+	matcher, ok := e.selector(sel)
+	if !ok {
+		return nil
+	}
+	return cascadia.QueryAll(root, matcher)
+}
+
+// selector returns the compiled form of sel. Selectors of extractors added
+// after init are compiled on each use.
+func (e SiteExtractor) selector(sel string) (cascadia.Sel, bool) {
+	if matcher, ok := compiledSelectors[sel]; ok {
+		return matcher, true
+	}
+	matcher, err := cascadia.Parse(sel)
+	if err != nil {
+		log.Printf("Invalid selector %q for %s: %v", sel, e.Name, err)
+		return nil, false
+	}
+	return matcher, true
+}
+
+// nodeText concatenates the text content beneath n
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(nodeText(c))
+	}
+	return sb.String()
+}
@@ -0,0 +1,155 @@
+package processors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func parseFixture(t *testing.T, name string) *html.Node {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", "site_extractors", name))
+	if err != nil {
+		t.Fatalf("opening fixture: %v", err)
+	}
+	defer f.Close()
+	doc, err := html.Parse(f)
+	if err != nil {
+		t.Fatalf("parsing fixture: %v", err)
+	}
+	return doc
+}
+
+func TestSiteExtractors(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		url      string
+		name     string
+		title    string
+		company  string
+		deadline string
+		// contains must appear in the description, excludes must not
+		contains []string
+		excludes []string
+	}{
+		{
+			fixture:  "careersmw.html",
+			url:      "https://careersmw.com/job/finance-officer-malawi-savings-bank/",
+			name:     "Careers MW",
+			title:    "Finance Officer",
+			company:  "Malawi Savings Bank",
+			deadline: "Closes: 30 November 2026",
+			contains: []string{"prepare monthly management accounts", "ACCA Part 2"},
+			excludes: []string{"inline script", "Share this", "Accountant at Press Corporation", "Apply for job", "Recent Jobs", "Driver at Illovo Sugar"},
+		},
+		{
+			fixture:  "jobsearchmalawi.html",
+			url:      "https://www.jobsearchmalawi.com/jobs/monitoring-and-evaluation-officer/",
+			name:     "JobSearch Malawi",
+			title:    "Monitoring and Evaluation Officer",
+			company:  "World Vision Malawi",
+			deadline: "Deadline: 15 December 2026",
+			contains: []string{"Lilongwe programme", "reporting on indicators"},
+			excludes: []string{"Share on LinkedIn", "How to apply", "Data Clerk at Action Aid", "Featured Employers"},
+		},
+		{
+			fixture:  "ntchito.html",
+			url:      "https://ntchito.com/job/software-developer",
+			name:     "Ntchito",
+			title:    "Software Developer",
+			company:  "Tech Innovations Ltd",
+			deadline: "Apply before 1 January 2027",
+			contains: []string{"build web applications in Go and React", "three years of experience"},
+			excludes: []string{"Share this", "Apply now", "Popular searches", "Browse jobs"},
+		},
+		{
+			fixture:  "unicef.html",
+			url:      "https://jobs.unicef.org/en-us/job/584123/education-specialist-noc-lilongwe-malawi",
+			name:     "UNICEF",
+			title:    "Education Specialist, NOC, Lilongwe, Malawi",
+			company:  "",
+			deadline: "Thu Nov 20 2026",
+			contains: []string{"most disadvantaged children", "monitoring of the country programme"},
+			excludes: []string{"Apply now", "Share on Facebook", "Similar jobs", "Health Officer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extractor, ok := FindSiteExtractor(tt.url)
+			if !ok {
+				t.Fatalf("FindSiteExtractor(%q) found no extractor", tt.url)
+			}
+			if extractor.Name != tt.name {
+				t.Fatalf("FindSiteExtractor(%q) = %q, want %q", tt.url, extractor.Name, tt.name)
+			}
+
+			doc := parseFixture(t, tt.fixture)
+			fields, ok := extractor.Extract(doc)
+			if !ok {
+				t.Fatal("Extract found no description")
+			}
+			if fields.Title != tt.title {
+				t.Errorf("Title = %q, want %q", fields.Title, tt.title)
+			}
+			if fields.Company != tt.company {
+				t.Errorf("Company = %q, want %q", fields.Company, tt.company)
+			}
+			if fields.Deadline != tt.deadline {
+				t.Errorf("Deadline = %q, want %q", fields.Deadline, tt.deadline)
+			}
+			if fields.ExtractionMethod != tt.name {
+				t.Errorf("ExtractionMethod = %q, want %q", fields.ExtractionMethod, tt.name)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(fields.Description, want) {
+					t.Errorf("Description is missing %q:\n%s", want, fields.Description)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(fields.Description, unwanted) {
+					t.Errorf("Description contains %q:\n%s", unwanted, fields.Description)
+				}
+			}
+
+			if content := extractor.ContentNode(doc); content == nil {
+				t.Error("ContentNode returned nil")
+			}
+		})
+	}
+}
+
+func TestSiteExtractorWithoutDescription(t *testing.T) {
+	extractor, ok := FindSiteExtractor("https://careersmw.com/")
+	if !ok {
+		t.Fatal("FindSiteExtractor found no extractor for careersmw.com")
+	}
+	doc, err := html.Parse(strings.NewReader(`<html><body><h1>Latest jobs</h1><p>Browse our listings.</p></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields, ok := extractor.Extract(doc); ok {
+		t.Errorf("Extract on a page without a description = %+v, want no match", fields)
+	}
+}
+
+func TestFindSiteExtractorUnknownHost(t *testing.T) {
+	if extractor, ok := FindSiteExtractor("https://example.com/jobs/1"); ok {
+		t.Errorf("FindSiteExtractor(example.com) = %q, want no extractor", extractor.Name)
+	}
+}
+
+func TestSiteExtractorSelectorsCompile(t *testing.T) {
+	for key, extractor := range SiteExtractors {
+		for _, list := range [][]string{extractor.Title, extractor.Company, extractor.Deadline, extractor.Description, extractor.Remove} {
+			for _, sel := range list {
+				if _, ok := compiledSelectors[sel]; !ok {
+					t.Errorf("%s: selector %q was not compiled at init", key, sel)
+				}
+			}
+		}
+	}
+}
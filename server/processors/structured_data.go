@@ -0,0 +1,273 @@
+package processors
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"easy-apply/models"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Extraction methods reported in JobPostingFields.ExtractionMethod for schema.org data
+const (
+	jobPostingMethodJSONLD    = "json-ld"
+	jobPostingMethodMicrodata = "microdata"
+)
+
+var (
+	jsonLDSelector           = cascadia.MustCompile(`script[type="application/ld+json"]`)
+	microdataPostingSelector = cascadia.MustCompile(`[itemscope][itemtype*="schema.org/JobPosting"]`)
+	itempropSelector         = cascadia.MustCompile(`[itemprop]`)
+)
+
+// extractJobPostingSchema looks for a schema.org JobPosting in the page's
+// JSON-LD blocks, then in microdata. ok is false if neither is present.
+func extractJobPostingSchema(doc *html.Node) (models.JobPostingFields, bool) {
+	for _, script := range cascadia.QueryAll(doc, jsonLDSelector) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(nodeText(script)), &data); err != nil {
+			continue
+		}
+		if posting := findJSONLDJobPosting(data); posting != nil {
+			fields := jobPostingFromJSONLD(posting)
+			fields.ExtractionMethod = jobPostingMethodJSONLD
+			return fields, fields.Title != "" || fields.Description != ""
+		}
+	}
+
+	if item := cascadia.Query(doc, microdataPostingSelector); item != nil {
+		fields := jobPostingFromMicrodata(item)
+		fields.ExtractionMethod = jobPostingMethodMicrodata
+		return fields, fields.Title != "" || fields.Description != ""
+	}
+
+	return models.JobPostingFields{}, false
+}
+
+// findJSONLDJobPosting walks a decoded JSON-LD value, including arrays and
+// @graph containers, for the first object typed JobPosting
+func findJSONLDJobPosting(data interface{}) map[string]interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if posting := findJSONLDJobPosting(item); posting != nil {
+				return posting
+			}
+		}
+	case map[string]interface{}:
+		if hasJSONLDType(v["@type"], "JobPosting") {
+			return v
+		}
+		if graph, ok := v["@graph"]; ok {
+			return findJSONLDJobPosting(graph)
+		}
+	}
+	return nil
+}
+
+// hasJSONLDType reports whether an @type value (string or array) includes want
+func hasJSONLDType(t interface{}, want string) bool {
+	switch v := t.(type) {
+	case string:
+		return v == want || strings.HasSuffix(v, "/"+want)
+	case []interface{}:
+		for _, item := range v {
+			if hasJSONLDType(item, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jobPostingFromJSONLD maps a JSON-LD JobPosting object onto JobPostingFields
+func jobPostingFromJSONLD(posting map[string]interface{}) models.JobPostingFields {
+	return models.JobPostingFields{
+		Title:          jsonLDText(posting["title"]),
+		Company:        jsonLDName(posting["hiringOrganization"]),
+		Location:       jsonLDLocation(posting["jobLocation"]),
+		Deadline:       jsonLDText(posting["validThrough"]),
+		DatePosted:     jsonLDText(posting["datePosted"]),
+		EmploymentType: jsonLDText(posting["employmentType"]),
+		Description:    htmlFragmentText(jsonLDText(posting["description"])),
+	}
+}
+
+// jsonLDText flattens a string, number or array of strings
+func jsonLDText(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			if s := jsonLDText(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	case map[string]interface{}:
+		return jsonLDName(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return ""
+}
+
+// jsonLDName reads the name of an Organization/Place value, which may be a plain string
+func jsonLDName(v interface{}) string {
+	if obj, ok := v.(map[string]interface{}); ok {
+		return jsonLDText(obj["name"])
+	}
+	if arr, ok := v.([]interface{}); ok && len(arr) > 0 {
+		return jsonLDName(arr[0])
+	}
+	return jsonLDText(v)
+}
+
+// jsonLDLocation flattens one or more Place values into "locality, region, country"
+func jsonLDLocation(v interface{}) string {
+	switch val := v.(type) {
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			if s := jsonLDLocation(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, "; ")
+	case map[string]interface{}:
+		address, ok := val["address"].(map[string]interface{})
+		if !ok {
+			if s := jsonLDText(val["address"]); s != "" {
+				return s
+			}
+			return jsonLDText(val["name"])
+		}
+		var parts []string
+		for _, key := range []string{"addressLocality", "addressRegion", "addressCountry"} {
+			if s := jsonLDName(address[key]); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	return jsonLDText(v)
+}
+
+// jobPostingFromMicrodata reads itemprop values scoped to a JobPosting item
+func jobPostingFromMicrodata(item *html.Node) models.JobPostingFields {
+	props := make(map[string]*html.Node)
+	for _, n := range cascadia.QueryAll(item, itempropSelector) {
+		if !belongsToItem(n, item) {
+			continue
+		}
+		for _, name := range strings.Fields(getAttr(n, "itemprop")) {
+			if _, seen := props[name]; !seen {
+				props[name] = n
+			}
+		}
+	}
+
+	value := func(name string) string {
+		n, ok := props[name]
+		if !ok {
+			return ""
+		}
+		// Nested items such as hiringOrganization carry their own name property
+		if hasAttr(n, "itemscope") {
+			for _, child := range cascadia.QueryAll(n, itempropSelector) {
+				if getAttr(child, "itemprop") == "name" {
+					return microdataValue(child)
+				}
+			}
+		}
+		return microdataValue(n)
+	}
+
+	return models.JobPostingFields{
+		Title:          value("title"),
+		Company:        value("hiringOrganization"),
+		Location:       value("jobLocation"),
+		Deadline:       value("validThrough"),
+		DatePosted:     value("datePosted"),
+		EmploymentType: value("employmentType"),
+		Description:    strings.TrimSpace(nodeText(propOrEmpty(props, "description"))),
+	}
+}
+
+// belongsToItem reports whether item is the nearest itemscope enclosing n,
+// so properties of nested items are not mistaken for the posting's own
+func belongsToItem(n, item *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p == item {
+			return true
+		}
+		if hasAttr(p, "itemscope") {
+			return false
+		}
+	}
+	return false
+}
+
+// microdataValue returns an itemprop's value following the microdata rules
+// for meta, time and link-like elements
+func microdataValue(n *html.Node) string {
+	switch n.Data {
+	case "meta":
+		return strings.TrimSpace(getAttr(n, "content"))
+	case "time":
+		if dt := getAttr(n, "datetime"); dt != "" {
+			return strings.TrimSpace(dt)
+		}
+	case "a", "link":
+		if !hasAttr(n, "itemscope") && n.FirstChild == nil {
+			return strings.TrimSpace(getAttr(n, "href"))
+		}
+	}
+	if c := getAttr(n, "content"); c != "" {
+		return strings.TrimSpace(c)
+	}
+	return strings.Join(strings.Fields(nodeText(n)), " ")
+}
+
+// htmlFragmentText converts an HTML fragment (as JSON-LD descriptions often are) to text
+func htmlFragmentText(fragment string) string {
+	if !strings.Contains(fragment, "<") {
+		return html.UnescapeString(fragment)
+	}
+	doc, err := html.Parse(strings.NewReader(fragment))
+	if err != nil {
+		return fragment
+	}
+	return strings.TrimSpace(nodeText(doc))
+}
+
+// propOrEmpty returns the node for an itemprop, or an empty text node if absent
+func propOrEmpty(props map[string]*html.Node, name string) *html.Node {
+	if n, ok := props[name]; ok {
+		return n
+	}
+	return &html.Node{Type: html.TextNode}
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrUnsafeURL is returned for user-supplied URLs that point at internal
+// addresses or use a scheme or port we do not fetch
+var ErrUnsafeURL = errors.New("URL is not allowed")
+
+// allowedURLSchemes and allowedURLPorts restrict what user-supplied URLs may target
+var (
+	allowedURLSchemes = map[string]bool{"http": true, "https": true}
+	allowedURLPorts   = map[string]bool{"": true, "80": true, "443": true, "8080": true, "8443": true}
+)
+
+// blockedPrefixes are special-purpose ranges not covered by the netip predicates
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),         // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),     // carrier-grade NAT, used by some cluster networks
+	netip.MustParsePrefix("192.0.0.0/24"),      // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),     // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),       // reserved, includes broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),      // NAT64, can map to internal IPv4
+	netip.MustParsePrefix("fd00:ec2::254/128"), // AWS IMDS over IPv6
+}
+
+// blockedHostnames are names that resolve to metadata or local services
+var blockedHostnames = map[string]bool{
+	"localhost":                true,
+	"metadata":                 true,
+	"metadata.google.internal": true,
+}
+
+// IsPublicIP reports whether ip is a globally routable unicast address
+func IsPublicIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURLTarget validates the scheme, port and hostname of a URL without resolving it
+func CheckURLTarget(u *url.URL) error {
+	if !allowedURLSchemes[strings.ToLower(u.Scheme)] {
+		return fmt.Errorf("%w: scheme %q is not supported", ErrUnsafeURL, u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: credentials in URLs are not supported", ErrUnsafeURL)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrUnsafeURL)
+	}
+	if !allowedURLPorts[u.Port()] {
+		return fmt.Errorf("%w: port %s is not allowed", ErrUnsafeURL, u.Port())
+	}
+	if blockedHostnames[host] || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") || strings.HasSuffix(host, ".local") {
+		return fmt.Errorf("%w: host %s is internal", ErrUnsafeURL, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !IsPublicIP(ip) {
+		return fmt.Errorf("%w: address %s is not public", ErrUnsafeURL, ip)
+	}
+	return nil
+}
+
+// ValidatePublicURL checks a user-supplied URL and resolves its host, rejecting
+// it unless every resolved address is public. The dialer used by Fetcher
+// repeats the address check at connect time, which covers redirects and DNS rebinding.
+func ValidatePublicURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsafeURL, err)
+	}
+	if err := CheckURLTarget(u); err != nil {
+		return err
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("could not resolve %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr) {
+			return fmt.Errorf("%w: %s resolves to non-public address %s", ErrUnsafeURL, u.Hostname(), addr)
+		}
+	}
+	return nil
+}
+
+// newSafeTransport returns a transport that refuses to connect to non-public
+// addresses. The check runs on the address actually dialed, after DNS resolution.
+func newSafeTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrUnsafeURL, err)
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !IsPublicIP(ip) {
+				return fmt.Errorf("%w: connection to %s blocked", ErrUnsafeURL, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// An outbound proxy would hide the real destination from the dial check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// checkRedirectTarget re-validates each redirect hop's scheme and port
+func checkRedirectTarget(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return CheckURLTarget(req.URL)
+}
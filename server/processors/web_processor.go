@@ -1,34 +1,140 @@
 package processors
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"regexp"
 	"strings"
 
+	"easy-apply/models"
+
 	"golang.org/x/net/html"
 )
 
 // WebProcessor handles web link processing
 type WebProcessor struct {
 	UploadDir string
+	Fetcher   *Fetcher
+	// Files extracts text from advert documents and images linked from job pages
+	Files *FileProcessor
+	// JunkPhrases are removed from extracted text
+	JunkPhrases []string
+	// Renderer renders JavaScript-only pages on its allowed domains when
+	// static extraction finds too little text; nil disables the fallback
+	Renderer *PageRenderer
+	// Format is the output format of extracted page text
+	Format TextFormat
+}
+
+// minStaticPageChars is the least page text accepted without trying the rendered fallback
+const minStaticPageChars = 300
+
+// DefaultJunkPhrases are navigation and sharing phrases common on job boards
+var DefaultJunkPhrases = []string{
+	"Post navigation", "Share this:", "Follow us", "Related Posts",
+	"Share on Facebook", "Share on WhatsApp", "Share on LinkedIn", "Click to share",
+	"Leave a Reply", "You may also like",
 }
 
-// NewWebProcessor creates a new web processor
+// NewWebProcessor creates a new web processor. Extra junk phrases can be
+// configured with WEB_JUNK_PHRASES, separated by "|", and WEB_OUTPUT_FORMAT=flat
+// selects the flat text output instead of Markdown.
 func NewWebProcessor(uploadDir string) *WebProcessor {
+	format := TextFormatMarkdown
+	if strings.EqualFold(os.Getenv("WEB_OUTPUT_FORMAT"), string(TextFormatFlat)) {
+		format = TextFormatFlat
+	}
+
+	junk := append([]string(nil), DefaultJunkPhrases...)
+	for _, phrase := range strings.Split(os.Getenv("WEB_JUNK_PHRASES"), "|") {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			junk = append(junk, phrase)
+		}
+	}
+
 	return &WebProcessor{
-		UploadDir: uploadDir,
+		UploadDir:   uploadDir,
+		Fetcher:     NewFetcher(),
+		Files:       NewFileProcessor(),
+		JunkPhrases: junk,
+		Renderer:    NewPageRendererFromEnv(),
+		Format:      format,
 	}
 }
 
+// JobPage is a scraped job posting together with the validators needed to
+// revalidate it with a conditional request
+type JobPage struct {
+	Text         string
+	Fields       models.JobPostingFields
+	ETag         string
+	LastModified string
+	// NotModified is set when the server confirmed the caller's copy is current;
+	// Text and Fields are then empty
+	NotModified bool
+}
+
 // ProcessWebLink extracts text from a webpage and returns the text content
-func (w *WebProcessor) ProcessWebLink(url string) (string, error) {
-	text, err := w.extractMainTextFromURL(url)
+// along with any structured job posting fields found on the page. Cancelling
+// ctx stops the fetch.
+func (w *WebProcessor) ProcessWebLink(ctx context.Context, url string) (string, models.JobPostingFields, error) {
+	page, err := w.FetchJobPage(ctx, url, "", "")
+	if err != nil {
+		return "", models.JobPostingFields{}, err
+	}
+	return page.Text, page.Fields, nil
+}
+
+// FetchJobPage scrapes a job posting, sending etag and lastModified (if set)
+// so an unchanged page is answered with 304 instead of being downloaded again
+func (w *WebProcessor) FetchJobPage(ctx context.Context, url, etag, lastModified string) (*JobPage, error) {
+	res, err := w.Fetcher.GetConditional(ctx, url, etag, lastModified)
 	if err != nil {
-		return "", fmt.Errorf("error extracting text from URL: %v", err)
+		return nil, fmt.Errorf("error extracting text from URL: %w", err)
 	}
 
-	return text, nil
+	page := &JobPage{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+	}
+	if res.StatusCode == http.StatusNotModified {
+		page.NotModified = true
+		return page, nil
+	}
+
+	page.Text, page.Fields, err = w.extractMainText(ctx, res.Body, res.URL)
+	if w.Renderer.Allowed(res.URL) && (err != nil || len(page.Text) < minStaticPageChars || w.Renderer.Forced(res.URL)) {
+		if rendered, renderErr := w.renderJobPage(ctx, res.URL, page.Fields); renderErr != nil {
+			log.Printf("Rendered fallback for %s failed: %v", res.URL, renderErr)
+		} else if len(rendered) > len(page.Text) {
+			page.Text, err = rendered, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error extracting text from URL: %w", err)
+	}
+	return page, nil
+}
+
+// renderJobPage renders a page in headless Chromium and extracts the text of
+// the resulting PDF, prefixed with any fields already found on the static page.
+// The PDF is never sent to OCR: a render with little text is just a failed render.
+func (w *WebProcessor) renderJobPage(ctx context.Context, url string, fields models.JobPostingFields) (string, error) {
+	pdfBytes, err := w.Renderer.RenderPDF(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	textOnly := *w.Files
+	textOnly.DisableOCR = true
+	text, _, err := textOnly.ProcessFileBuffer(ctx, pdfBytes, ".pdf")
+	if err != nil {
+		return "", fmt.Errorf("%w: extracting rendered PDF: %v", ErrRenderFailed, err)
+	}
+	return jobPostingText(fields, w.renderPlainText(text)), nil
 }
 
 // Extract raw text from HTML node
@@ -64,27 +170,6 @@ func (w *WebProcessor) findMainContentNode(n *html.Node, tags []string) *html.No
 	return match
 }
 
-// Fallback: Find <div> with most text
-func (w *WebProcessor) findLargestTextDiv(n *html.Node) *html.Node {
-	var best *html.Node
-	maxLen := 0
-	var f func(*html.Node)
-	f = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "div" {
-			text := strings.TrimSpace(w.extractText(n))
-			if len(text) > maxLen {
-				maxLen = len(text)
-				best = n
-			}
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			f(c)
-		}
-	}
-	f(n)
-	return best
-}
-
 // Clean up text for LLMs: removes junk and normalizes spacing
 func (w *WebProcessor) cleanText(text string) string {
 	// Remove excessive whitespace and line breaks
@@ -96,10 +181,7 @@ func (w *WebProcessor) cleanText(text string) string {
 	text = regexp.MustCompile(`([.!?])\s+`).ReplaceAllString(text, "$1\n")
 
 	// Remove nav/footer junk phrases
-	junk := []string{
-		"Post navigation", "Share this:", "Follow us", "Related Posts",
-	}
-	for _, phrase := range junk {
+	for _, phrase := range w.JunkPhrases {
 		text = strings.ReplaceAll(text, phrase, "")
 	}
 
@@ -109,33 +191,99 @@ func (w *WebProcessor) cleanText(text string) string {
 	return text
 }
 
-// Fetch and extract cleaned text from a webpage
-func (w *WebProcessor) extractMainTextFromURL(url string) (string, error) {
-	resp, err := http.Get(url)
+// Extract cleaned text from a fetched webpage, merging in the text of any
+// advert documents or images attached to its main content
+func (w *WebProcessor) extractMainText(ctx context.Context, body []byte, url string) (string, models.JobPostingFields, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return "", models.JobPostingFields{}, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP Error: %s", resp.Status)
+	text, fields, content, err := w.extractPageText(doc, url)
+	if content == nil {
+		content = doc
+	}
+	advert := w.extractAttachments(ctx, content, url, len(text))
+	if advert == "" {
+		if err != nil {
+			return "", models.JobPostingFields{}, err
+		}
+		return text, fields, nil
 	}
 
-	doc, err := html.Parse(resp.Body)
+	// Pages that are only a heading plus the advert have no usable main content
 	if err != nil {
-		return "", err
+		return jobPostingText(fields, advert), fields, nil
 	}
+	return text + "\n\n" + advert, fields, nil
+}
+
+// extractPageText returns the page's job posting text and fields along with
+// the node the text was taken from (nil when it came from structured data only)
+func (w *WebProcessor) extractPageText(doc *html.Node, url string) (string, models.JobPostingFields, *html.Node, error) {
+	// schema.org JobPosting data is the most reliable source of the title,
+	// company and dates, so it takes precedence over scraped values
+	fields, hasSchema := extractJobPostingSchema(doc)
 
+	// Known job boards get their own selector rules; anything else, or a
+	// board whose layout no longer matches, falls through to the heuristic
+	if extractor, ok := FindSiteExtractor(url); ok {
+		if siteFields, ok := extractor.Extract(doc); ok {
+			fields = mergeJobPostingFields(fields, siteFields)
+			content := extractor.ContentNode(doc)
+			return jobPostingText(fields, w.renderContent(content, url)), fields, content, nil
+		}
+		log.Printf("Site extractor %s found no description on %s, using generic extraction", extractor.Name, url)
+	}
+
+	// Score content inside <main>/<article> when the page has one, so
+	// page chrome outside it can never win
+	stripBoilerplate(doc)
 	mainTags := []string{"main", "article"}
-	mainNode := w.findMainContentNode(doc, mainTags)
-	if mainNode == nil {
-		mainNode = w.findLargestTextDiv(doc)
+	root := w.findMainContentNode(doc, mainTags)
+	if root == nil {
+		root = doc
+	}
+	mainNode := w.findBestContentNode(root)
+	if mainNode == nil && root != doc {
+		mainNode = root
 	}
 	if mainNode == nil {
-		return "", fmt.Errorf("could not find main content")
+		if hasSchema && fields.Description != "" {
+			return jobPostingText(fields, w.renderPlainText(fields.Description)), fields, nil, nil
+		}
+		return "", fields, nil, fmt.Errorf("could not find main content")
+	}
+
+	cleanedText := w.renderContent(mainNode, url)
+	if !hasSchema {
+		return cleanedText, fields, mainNode, nil
 	}
+	return jobPostingText(fields, cleanedText), fields, mainNode, nil
+}
 
-	rawText := w.extractText(mainNode)
-	cleanedText := w.cleanText(rawText)
-	return cleanedText, nil
+// mergeJobPostingFields fills the empty fields of primary from fallback
+func mergeJobPostingFields(primary, fallback models.JobPostingFields) models.JobPostingFields {
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&primary.Title, fallback.Title},
+		{&primary.Company, fallback.Company},
+		{&primary.Location, fallback.Location},
+		{&primary.Deadline, fallback.Deadline},
+		{&primary.DatePosted, fallback.DatePosted},
+		{&primary.EmploymentType, fallback.EmploymentType},
+		{&primary.Description, fallback.Description},
+	} {
+		if *f.dst == "" {
+			*f.dst = f.src
+		}
+	}
+	if primary.ExtractionMethod == "" {
+		primary.ExtractionMethod = fallback.ExtractionMethod
+	} else if fallback.ExtractionMethod != "" {
+		primary.ExtractionMethod += "+" + fallback.ExtractionMethod
+	}
+	return primary
 }
@@ -0,0 +1,349 @@
+package prompts
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Errors returned by the registry
+var (
+	ErrUnknownPrompt  = errors.New("unknown prompt")
+	ErrUnknownVersion = errors.New("unknown prompt version")
+	ErrInvalidVars    = errors.New("invalid prompt variables")
+)
+
+// Prompt names
+const (
+	ResumeSystem         = "resume_system"
+	ResumeUser           = "resume_user"
+	ResumeRevision       = "resume_revision"
+	CoverLetterSystem    = "cover_letter_system"
+	CoverLetterUser      = "cover_letter_user"
+	SubjectSystem        = "subject_system"
+	SubjectExample       = "subject_example"
+	RecommendationSystem = "recommendation_system"
+	ListingSystem        = "listing_system"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// VarType is the declared type of a template variable
+type VarType string
+
+// Variable types. A list is a []string.
+const (
+	TypeString VarType = "string"
+	TypeInt    VarType = "int"
+	TypeBool   VarType = "bool"
+	TypeList   VarType = "list"
+)
+
+// Vars holds the values a template is rendered with
+type Vars map[string]any
+
+// manifest is templates/manifest.json: every prompt, its variables and its
+// versions with their A/B weights
+type manifest map[string]struct {
+	Variables map[string]VarType `json:"variables"`
+	Versions  map[string]struct {
+		File   string `json:"file"`
+		Weight int    `json:"weight"`
+	} `json:"versions"`
+}
+
+// Version is one revision of a prompt
+type Version struct {
+	Name    string
+	Version string
+	Weight  int
+	tmpl    *template.Template
+}
+
+type prompt struct {
+	name      string
+	variables map[string]VarType
+	versions  []*Version // sorted by version
+	pinned    string
+}
+
+// Registry holds the loaded prompt templates
+type Registry struct {
+	prompts map[string]*prompt
+}
+
+// Rendered is a prompt rendered for one call
+type Rendered struct {
+	Name    string
+	Version string
+	Text    string
+}
+
+// ID identifies the prompt revision, e.g. "resume_system@v1"
+func (r Rendered) ID() string {
+	return r.Name + "@" + r.Version
+}
+
+// Load reads manifest.json, the shared partials in partials/*.tmpl and every
+// template the manifest names from fsys, and checks that each version renders
+// with its prompt's variables.
+//
+// PROMPT_<NAME>_VERSION pins a prompt to one version and
+// PROMPT_<NAME>_WEIGHTS (e.g. "v1=80,v2=20") overrides its A/B weights.
+func Load(fsys fs.FS) (*Registry, error) {
+	data, err := fs.ReadFile(fsys, "manifest.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt manifest: %w", err)
+	}
+
+	partials, err := fs.Glob(fsys, "partials/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt partials: %w", err)
+	}
+
+	registry := &Registry{prompts: make(map[string]*prompt, len(m))}
+	for name, spec := range m {
+		for variable, varType := range spec.Variables {
+			switch varType {
+			case TypeString, TypeInt, TypeBool, TypeList:
+			default:
+				return nil, fmt.Errorf("prompt %s: variable %s has unknown type %q", name, variable, varType)
+			}
+		}
+		if len(spec.Versions) == 0 {
+			return nil, fmt.Errorf("prompt %s has no versions", name)
+		}
+
+		p := &prompt{name: name, variables: spec.Variables}
+		for version, file := range spec.Versions {
+			tmpl := template.New(path.Base(file.File)).Option("missingkey=error")
+			for _, partial := range partials {
+				if tmpl, err = parseFile(fsys, tmpl, partial); err != nil {
+					return nil, err
+				}
+			}
+			if tmpl, err = parseFile(fsys, tmpl, file.File); err != nil {
+				return nil, err
+			}
+			v := &Version{Name: name, Version: version, Weight: file.Weight, tmpl: tmpl}
+			if _, err := v.render(sampleVars(spec.Variables)); err != nil {
+				return nil, fmt.Errorf("prompt %s@%s does not render: %w", name, version, err)
+			}
+			p.versions = append(p.versions, v)
+		}
+		sort.Slice(p.versions, func(i, j int) bool { return p.versions[i].Version < p.versions[j].Version })
+
+		if err := p.configure(); err != nil {
+			return nil, err
+		}
+		registry.prompts[name] = p
+	}
+	return registry, nil
+}
+
+func parseFile(fsys fs.FS, tmpl *template.Template, file string) (*template.Template, error) {
+	text, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template %s: %w", file, err)
+	}
+	tmpl, err = tmpl.New(path.Base(file)).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template %s: %w", file, err)
+	}
+	return tmpl, nil
+}
+
+// configure applies the version pin and weight overrides from the environment
+func (p *prompt) configure() error {
+	prefix := "PROMPT_" + strings.ToUpper(p.name) + "_"
+	if pinned := strings.TrimSpace(os.Getenv(prefix + "VERSION")); pinned != "" {
+		if p.version(pinned) == nil {
+			return fmt.Errorf("%w %q for prompt %s", ErrUnknownVersion, pinned, p.name)
+		}
+		p.pinned = pinned
+	}
+	if weights := strings.TrimSpace(os.Getenv(prefix + "WEIGHTS")); weights != "" {
+		for _, v := range p.versions {
+			v.Weight = 0
+		}
+		for _, pair := range strings.Split(weights, ",") {
+			version, weight, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			v := p.version(version)
+			n, err := strconv.Atoi(weight)
+			if v == nil || err != nil || n < 0 {
+				return fmt.Errorf("invalid %sWEIGHTS entry %q", prefix, pair)
+			}
+			v.Weight = n
+		}
+	}
+	total := 0
+	for _, v := range p.versions {
+		total += v.Weight
+	}
+	if total == 0 && p.pinned == "" {
+		return fmt.Errorf("prompt %s has no version with a positive weight", p.name)
+	}
+	return nil
+}
+
+func (p *prompt) version(version string) *Version {
+	for _, v := range p.versions {
+		if v.Version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+// assign picks the version for subject. The same subject always gets the
+// same version while the weights are unchanged; an empty subject is assigned at random.
+func (p *prompt) assign(subject string) *Version {
+	if p.pinned != "" {
+		return p.version(p.pinned)
+	}
+	total := 0
+	for _, v := range p.versions {
+		total += v.Weight
+	}
+	var bucket int
+	if subject == "" {
+		bucket = rand.Intn(total)
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(p.name + "\x00" + subject))
+		bucket = int(h.Sum32() % uint32(total))
+	}
+	for _, v := range p.versions {
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+	return p.versions[len(p.versions)-1]
+}
+
+// Render assigns a version of prompt name to subject (a user or listing ID
+// used for A/B assignment) and renders it with vars, which must match the
+// prompt's declared variables exactly.
+func (r *Registry) Render(name, subject string, vars Vars) (Rendered, error) {
+	p, ok := r.prompts[name]
+	if !ok {
+		return Rendered{}, fmt.Errorf("%w %q", ErrUnknownPrompt, name)
+	}
+	if err := p.check(vars); err != nil {
+		return Rendered{}, err
+	}
+	v := p.assign(subject)
+	text, err := v.render(vars)
+	if err != nil {
+		return Rendered{}, fmt.Errorf("failed to render prompt %s@%s: %w", name, v.Version, err)
+	}
+	return Rendered{Name: name, Version: v.Version, Text: text}, nil
+}
+
+// check verifies that vars supplies every declared variable with its type and nothing else
+func (p *prompt) check(vars Vars) error {
+	for variable, varType := range p.variables {
+		value, ok := vars[variable]
+		if !ok {
+			return fmt.Errorf("%w: prompt %s is missing %s", ErrInvalidVars, p.name, variable)
+		}
+		if !hasType(value, varType) {
+			return fmt.Errorf("%w: prompt %s variable %s must be a %s, got %T", ErrInvalidVars, p.name, variable, varType, value)
+		}
+	}
+	for variable := range vars {
+		if _, ok := p.variables[variable]; !ok {
+			return fmt.Errorf("%w: prompt %s has no variable %s", ErrInvalidVars, p.name, variable)
+		}
+	}
+	return nil
+}
+
+func hasType(value any, varType VarType) bool {
+	switch value.(type) {
+	case string:
+		return varType == TypeString
+	case int:
+		return varType == TypeInt
+	case bool:
+		return varType == TypeBool
+	case []string:
+		return varType == TypeList
+	}
+	return false
+}
+
+func sampleVars(variables map[string]VarType) Vars {
+	vars := make(Vars, len(variables))
+	for variable, varType := range variables {
+		switch varType {
+		case TypeString:
+			vars[variable] = "sample"
+		case TypeInt:
+			vars[variable] = 1
+		case TypeBool:
+			vars[variable] = true
+		case TypeList:
+			vars[variable] = []string{"sample"}
+		}
+	}
+	return vars
+}
+
+// render executes the template; surrounding whitespace is trimmed
+func (v *Version) render(vars Vars) (string, error) {
+	var sb strings.Builder
+	if err := v.tmpl.Execute(&sb, map[string]any(vars)); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+	defaultErr      error
+)
+
+// Initialize loads the default registry: from PROMPTS_DIR when set, so
+// prompts can be edited without a rebuild, otherwise from the templates
+// built into the binary. Call it at startup to fail fast on a broken template.
+func Initialize() error {
+	defaultOnce.Do(func() {
+		var fsys fs.FS
+		if dir := os.Getenv("PROMPTS_DIR"); dir != "" {
+			fsys = os.DirFS(dir)
+			log.Printf("Loading prompt templates from %s", dir)
+		} else {
+			fsys, _ = fs.Sub(embedded, "templates")
+		}
+		defaultRegistry, defaultErr = Load(fsys)
+	})
+	return defaultErr
+}
+
+// Render renders a prompt from the default registry, loading it on first use
+func Render(name, subject string, vars Vars) (Rendered, error) {
+	if err := Initialize(); err != nil {
+		return Rendered{}, err
+	}
+	return defaultRegistry.Render(name, subject, vars)
+}
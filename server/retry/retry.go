@@ -0,0 +1,231 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Class is the kind of failure an error represents, which decides whether
+// it is worth retrying
+type Class string
+
+// Error classes
+const (
+	ClassRateLimit      Class = "rate_limit"
+	ClassTimeout        Class = "timeout"
+	ClassServer         Class = "server"
+	ClassNetwork        Class = "network"
+	ClassInvalidRequest Class = "invalid_request"
+	ClassContentFilter  Class = "content_filter"
+	ClassCanceled       Class = "canceled"
+	ClassUnknown        Class = "unknown"
+)
+
+// Retryable reports whether a failure of this class may succeed on a later attempt
+func (c Class) Retryable() bool {
+	switch c {
+	case ClassInvalidRequest, ClassContentFilter, ClassCanceled:
+		return false
+	}
+	return true
+}
+
+// Error is a classified failure of an external call. RetryAfter is the wait
+// the service asked for, if any.
+type Error struct {
+	Class      Class
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError classifies err explicitly, for failures only the caller can recognise
+func NewError(class Class, err error) *Error {
+	return &Error{Class: class, Err: err}
+}
+
+// StatusError classifies an HTTP response that was not successful, taking
+// any Retry-After header into account
+func StatusError(resp *http.Response, err error) *Error {
+	return &Error{
+		Class:      ClassifyStatus(resp.StatusCode),
+		StatusCode: resp.StatusCode,
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After")),
+		Err:        err,
+	}
+}
+
+// ClassifyStatus maps an HTTP status code to a class
+func ClassifyStatus(code int) Class {
+	switch {
+	case code == http.StatusTooManyRequests:
+		return ClassRateLimit
+	case code == http.StatusRequestTimeout || code == http.StatusGatewayTimeout:
+		return ClassTimeout
+	case code >= 500:
+		return ClassServer
+	case code >= 400:
+		return ClassInvalidRequest
+	}
+	return ClassUnknown
+}
+
+// Classify returns the class of err and the wait the service asked for.
+// Errors that are not recognised are treated as retryable.
+func Classify(err error) (Class, time.Duration) {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Class, classified.RetryAfter
+	}
+	if errors.Is(err, context.Canceled) {
+		return ClassCanceled, 0
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ClassTimeout, 0
+	}
+
+	// Google API errors carry a gRPC status or an HTTP code
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		switch grpcErr.GRPCStatus().Code() {
+		case codes.ResourceExhausted:
+			return ClassRateLimit, 0
+		case codes.DeadlineExceeded:
+			return ClassTimeout, 0
+		case codes.Unavailable, codes.Internal, codes.Unknown, codes.Aborted:
+			return ClassServer, 0
+		case codes.InvalidArgument, codes.FailedPrecondition, codes.PermissionDenied,
+			codes.Unauthenticated, codes.NotFound, codes.OutOfRange, codes.Unimplemented:
+			return ClassInvalidRequest, 0
+		case codes.Canceled:
+			return ClassCanceled, 0
+		}
+	}
+	var httpErr interface{ HTTPCode() int }
+	if errors.As(err, &httpErr) && httpErr.HTTPCode() > 0 {
+		return ClassifyStatus(httpErr.HTTPCode()), 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ClassTimeout, 0
+		}
+		return ClassNetwork, 0
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return ClassNetwork, 0
+	}
+	return ClassUnknown, 0
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// Policy retries retryable failures with exponential backoff and jitter.
+// A Retry-After longer than MaxRetryAfter ends the retries rather than
+// being waited out, as does a wait that would run past the context deadline.
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxRetryAfter  time.Duration
+}
+
+// Default is the policy for calls with no particular needs
+var Default = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	MaxRetryAfter:  30 * time.Second,
+}
+
+// Backoff returns the delay before the retry following a zero-based attempt:
+// exponential from InitialBackoff, capped at MaxBackoff, with half of it jittered
+func (p Policy) Backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff << attempt
+	if delay <= 0 || (p.MaxBackoff > 0 && delay > p.MaxBackoff) {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable,
+// or runs out of attempts or time. attempt counts from 1. op names the call
+// in logs and in the error returned after the last attempt.
+func Do[T any](ctx context.Context, p Policy, op string, fn func(attempt int) (T, error)) (T, error) {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	var zero T
+	for attempt := 1; ; attempt++ {
+		result, err := fn(attempt)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return result, err
+		}
+
+		class, retryAfter := Classify(err)
+		if !class.Retryable() {
+			return result, err
+		}
+		if attempt >= attempts {
+			return result, fmt.Errorf("%s failed after %d attempts: %w", op, attempt, err)
+		}
+
+		wait := p.Backoff(attempt - 1)
+		if retryAfter > 0 {
+			if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+				return result, fmt.Errorf("%s: service asked to retry after %v: %w", op, retryAfter, err)
+			}
+			wait = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return result, fmt.Errorf("%s: no time left to retry: %w", op, err)
+		}
+		log.Printf("%s attempt %d/%d failed (%s): %v; retrying in %v", op, attempt, attempts, class, err, wait)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return zero, fmt.Errorf("%s: %w (last error: %v)", op, ctx.Err(), err)
+		}
+	}
+}
@@ -17,6 +17,8 @@ func setupRoutes(sentryHandler *sentryhttp.Handler) {
 	http.HandleFunc("/convert-pdf", sentryHandler.HandleFunc(middleware.WithCORS(convertPDFHandler)))
 	http.HandleFunc("/recommendations", sentryHandler.HandleFunc(middleware.WithCORS(handlers.JobRecommendationsHandler)))
 	http.HandleFunc("/validate-url", sentryHandler.HandleFunc(middleware.WithCORS(handlers.ValidateURLHandler)))
+	http.HandleFunc("/usage", sentryHandler.HandleFunc(middleware.WithCORS(handlers.UsageReportHandler)))
+	http.HandleFunc("/cover-letter", sentryHandler.HandleFunc(middleware.WithCORS(handlers.CoverLetterHandler)))
 	// http.HandleFunc("/events", sentryHandler.HandleFunc(middleware.WithCORS(middleware.WithSSE(TestHandler))))
 	http.HandleFunc("/events/", sentryHandler.HandleFunc(middleware.WithCORS(sse.EventsHandler)))
 }
\ No newline at end of file
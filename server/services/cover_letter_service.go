@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"easy-apply/models"
+	"easy-apply/processors"
+	"easy-apply/prompts"
+	"easy-apply/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrInvalidCoverLetterOptions is returned for a tone, length or other option the service does not support.
+var ErrInvalidCoverLetterOptions = errors.New("invalid cover letter options")
+
+// Cover letter tones
+const (
+	CoverLetterToneFormal  = "formal"
+	CoverLetterToneWarm    = "warm"
+	CoverLetterToneConcise = "concise"
+)
+
+// Cover letter lengths
+const (
+	CoverLetterLengthShort  = "short"
+	CoverLetterLengthMedium = "medium"
+	CoverLetterLengthLong   = "long"
+)
+
+const (
+	maxCoverLetterHooks           = 5
+	maxCoverLetterHookLength      = 300
+	maxCoverLetterAddresseeLength = 120
+)
+
+// coverLetterWords is the word range of each length; medium matches the letters written with the resume
+var coverLetterWords = map[string][2]int{
+	CoverLetterLengthShort:  {150, 250},
+	CoverLetterLengthMedium: {250, 400},
+	CoverLetterLengthLong:   {400, 600},
+}
+
+// NormalizeCoverLetterOptions fills in defaults (formal, medium) and validates the options.
+// Language is left to DetectLanguages, which resolves it to a code.
+func NormalizeCoverLetterOptions(options models.CoverLetterOptions) (models.CoverLetterOptions, error) {
+	options.Tone = strings.ToLower(strings.TrimSpace(options.Tone))
+	switch options.Tone {
+	case "":
+		options.Tone = CoverLetterToneFormal
+	case CoverLetterToneFormal, CoverLetterToneWarm, CoverLetterToneConcise:
+	default:
+		return options, fmt.Errorf("%w: unknown tone %q", ErrInvalidCoverLetterOptions, options.Tone)
+	}
+
+	options.Length = strings.ToLower(strings.TrimSpace(options.Length))
+	if options.Length == "" {
+		options.Length = CoverLetterLengthMedium
+	}
+	if _, ok := coverLetterWords[options.Length]; !ok {
+		return options, fmt.Errorf("%w: unknown length %q", ErrInvalidCoverLetterOptions, options.Length)
+	}
+
+	options.Language = strings.TrimSpace(options.Language)
+	options.Addressee = strings.TrimSpace(options.Addressee)
+	if len(options.Addressee) > maxCoverLetterAddresseeLength {
+		return options, fmt.Errorf("%w: addressee must be at most %d characters", ErrInvalidCoverLetterOptions, maxCoverLetterAddresseeLength)
+	}
+
+	var hooks []string
+	for _, hook := range options.Hooks {
+		if hook = strings.TrimSpace(hook); hook != "" {
+			hooks = append(hooks, hook)
+		}
+	}
+	if len(hooks) > maxCoverLetterHooks {
+		return options, fmt.Errorf("%w: at most %d hooks are allowed", ErrInvalidCoverLetterOptions, maxCoverLetterHooks)
+	}
+	for _, hook := range hooks {
+		if len(hook) > maxCoverLetterHookLength {
+			return options, fmt.Errorf("%w: hooks must be at most %d characters", ErrInvalidCoverLetterOptions, maxCoverLetterHookLength)
+		}
+	}
+	options.Hooks = hooks
+	return options, nil
+}
+
+// GenerateCoverLetter writes a cover letter for a job from the resume, independently of the
+// resume generation. options must already be normalized, with Language set to a language code.
+// PII in the resume is replaced with placeholders before the call and restored in the letter. When onDelta is set, partial letter
+// text is passed to it while generation runs. It returns the letter and the prompt version used.
+func GenerateCoverLetter(ctx context.Context, resumeText, jobPosting, jobTitle, company string, options models.CoverLetterOptions, onDelta DocumentDeltaFunc) (letter string, promptVersion string, err error) {
+	span := sentry.StartSpan(ctx, "openai.generate_cover_letter")
+	defer func() {
+		if err != nil {
+			span.SetTag("error", "true")
+			span.SetData("error_message", err.Error())
+			span.Status = sentry.SpanStatusAborted
+		}
+		span.Finish()
+	}()
+	span.SetData("tone", options.Tone)
+	span.SetData("length", options.Length)
+	span.SetData("language", options.Language)
+	span.SetData("hooks", len(options.Hooks))
+
+	if openAIProcessor == nil {
+		err = fmt.Errorf("OpenAIProcessor not initialized in openai_service")
+		utils.Logger.Println(err.Error())
+		return "", "", err
+	}
+
+	redactedResume, redaction, _ := redactResume(span, resumeText)
+	words := coverLetterWords[options.Length]
+	userMessage, err := prompts.Render(prompts.CoverLetterUser, processors.LLMUsageScopeFrom(ctx).UserID, prompts.Vars{
+		"jobPosting": strings.TrimSpace(jobPosting),
+		"resume":     strings.TrimSpace(redactedResume),
+		"jobTitle":   jobTitle,
+		"company":    company,
+		"tone":       options.Tone,
+		"minWords":   words[0],
+		"maxWords":   words[1],
+		"language":   processors.LanguageName(options.Language),
+		"addressee":  options.Addressee,
+		"hooks":      options.Hooks,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	var onText func(string)
+	if onDelta != nil {
+		stream := newDocumentStream(onDelta, redaction)
+		defer stream.flush()
+		onText = stream.onText
+	}
+
+	startTime := time.Now()
+	resp, err := openAIProcessor.GenerateCoverLetter(span.Context(), userMessage, onText)
+	span.SetData("duration_ms", time.Since(startTime).Milliseconds())
+	if err != nil {
+		recordLLMError(span, err)
+		return "", "", fmt.Errorf("cover letter generation failed: %w", err)
+	}
+	span.SetData("prompt_version", resp.PromptVersion)
+
+	var result struct {
+		CoverLetter string `json:"generated_cover_letter"`
+	}
+	if err = json.Unmarshal([]byte(resp.Text), &result); err != nil {
+		return "", "", fmt.Errorf("failed to parse cover letter JSON: %w", err)
+	}
+	letter = redaction.Restore(result.CoverLetter)
+	span.SetData("cover_letter_length", len(letter))
+	return letter, resp.PromptVersion, nil
+}
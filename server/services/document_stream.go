@@ -0,0 +1,102 @@
+package services
+
+import (
+	"easy-apply/models"
+	"easy-apply/processors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// documentStreamInterval throttles partial document updates so a fast stream
+// does not overflow the client's SSE buffer
+const documentStreamInterval = 250 * time.Millisecond
+
+// streamedDocuments maps generated JSON fields to the names used in UploadResponse
+var streamedDocuments = []struct{ field, document string }{
+	{"generated_resume", "resume"},
+	{"generated_cover_letter", "coverLetter"},
+}
+
+// unfinishedPlaceholder matches a PII placeholder cut off at the end of a chunk
+var unfinishedPlaceholder = regexp.MustCompile(`\[[A-Z_0-9]{0,24}$`)
+
+// DocumentDeltaFunc receives partial document text as it is generated
+type DocumentDeltaFunc func(delta models.DocumentDelta)
+
+// documentStream turns the raw JSON stream of a resume generation into
+// per-document deltas, with PII placeholders restored
+type documentStream struct {
+	send      DocumentDeltaFunc
+	redaction *processors.Redaction
+
+	mu       sync.Mutex
+	sent     map[string]string
+	lastSent time.Time
+	pending  string
+}
+
+func newDocumentStream(send DocumentDeltaFunc, redaction *processors.Redaction) *documentStream {
+	return &documentStream{send: send, redaction: redaction, sent: make(map[string]string)}
+}
+
+// onText is passed to the LLM router; it forwards new text at most once per interval
+func (s *documentStream) onText(raw string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = raw
+	if time.Since(s.lastSent) < documentStreamInterval {
+		return
+	}
+	s.emit()
+}
+
+// flush sends whatever arrived since the last update
+func (s *documentStream) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.emit()
+}
+
+// replace sends the final text of a document that was changed after generation
+func (s *documentStream) replace(document, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.send(models.DocumentDelta{Document: document, Text: text, Reset: true})
+	s.sent[document] = text
+}
+
+func (s *documentStream) emit() {
+	if s.pending == "" {
+		return
+	}
+	s.lastSent = time.Now()
+	fields := processors.PartialJSONFields(s.pending)
+	s.pending = ""
+
+	for _, d := range streamedDocuments {
+		text, ok := fields[d.field]
+		if !ok {
+			continue
+		}
+		// Hold back a placeholder until it is complete so it can be restored
+		if loc := unfinishedPlaceholder.FindStringIndex(text); loc != nil {
+			text = text[:loc[0]]
+		}
+		text = s.redaction.Restore(text)
+
+		previous := s.sent[d.document]
+		switch {
+		case text == previous:
+			continue
+		case strings.HasPrefix(text, previous):
+			s.send(models.DocumentDelta{Document: d.document, Offset: utf8.RuneCountInString(previous), Text: text[len(previous):]})
+		default:
+			// A retried or repaired reply started over
+			s.send(models.DocumentDelta{Document: d.document, Text: text, Reset: true})
+		}
+		s.sent[d.document] = text
+	}
+}
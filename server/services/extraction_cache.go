@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"easy-apply/models"
+	"easy-apply/processors"
+	"easy-apply/utils"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	// maxCachedTextBytes keeps cached extractions well inside Firestore's 1MB document limit.
+	maxCachedTextBytes = 900 << 10
+	// extractionCacheTTL is how long a user's extracted resume text is kept for reuse.
+	extractionCacheTTL = 7 * 24 * time.Hour
+)
+
+// ExtractionCache stores each user's extraction results keyed by content hash and
+// extractor version. Get returns nil without an error on a cache miss or an expired entry.
+type ExtractionCache interface {
+	Get(ctx context.Context, userID, key string) (*models.CachedExtraction, error)
+	Set(ctx context.Context, userID, key string, entry *models.CachedExtraction) error
+}
+
+var extractionCache ExtractionCache
+
+// InitializeExtractionCache sets the persistent store used to deduplicate file extraction.
+func InitializeExtractionCache(cache ExtractionCache) {
+	extractionCache = cache
+	utils.Logger.Println("FileService initialized with extraction cache.")
+}
+
+// ExtractionCacheKey derives the cache key for a file from its SHA-256 and the extractor version.
+func ExtractionCacheKey(fileContent []byte) string {
+	sum := sha256.Sum256(fileContent)
+	return hex.EncodeToString(sum[:]) + "-v" + processors.ExtractorVersion
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"bytes"
 	"context"
+	"easy-apply/models"
 	"easy-apply/processors" // Assuming this is the correct path to your processors package
 	"easy-apply/utils"      // For utils.Logger
 	"fmt"
@@ -41,8 +42,10 @@ func ProcessFileContent(ctx context.Context, file io.Reader, filename string) ([
 	return buf.Bytes(), nil
 }
 
-// ExtractTextFromFile extracts text from the given file content and extension.
-func ExtractTextFromFile(ctx context.Context, fileContent []byte, fileExt string) (string, error) {
+// ExtractTextFromFile extracts text from the given file content and extension,
+// along with a report on the quality of the extraction. Results are cached for
+// userID; an empty userID skips the cache.
+func ExtractTextFromFile(ctx context.Context, userID string, fileContent []byte, fileExt string) (string, models.ExtractionReport, error) {
 	span := sentry.StartSpan(ctx, "file.extract_text")
 	defer span.Finish()
 	span.SetData("file_ext", fileExt)
@@ -54,11 +57,22 @@ func ExtractTextFromFile(ctx context.Context, fileContent []byte, fileExt string
 		span.SetTag("error", "true")
 		span.SetData("error_message", err.Error())
 		span.Status = sentry.SpanStatusInternalError
-		return "", err
+		return "", models.ExtractionReport{}, err
 	}
 
+	cacheKey := ExtractionCacheKey(fileContent)
+	if cached := lookupCachedExtraction(ctx, userID, cacheKey); cached != nil {
+		span.SetTag("extraction_cache", "hit")
+		span.SetData("cache_hit", true)
+		span.SetData("extracted_text_length", len(cached.Text))
+		utils.Logger.Printf("Extraction cache hit for %s", cacheKey)
+		return cached.Text, cached.Report, nil
+	}
+	span.SetTag("extraction_cache", "miss")
+	span.SetData("cache_hit", false)
+
 	startTime := time.Now()
-	extractedText, err := fileProcessor.ProcessFileBuffer(fileContent, fileExt) // Assumes ProcessFileBuffer is a method of FileProcessor
+	extractedText, report, err := fileProcessor.ProcessFileBuffer(ctx, fileContent, fileExt) // Assumes ProcessFileBuffer is a method of FileProcessor
 	duration := time.Since(startTime)
 	span.SetData("duration_ms", duration.Milliseconds())
 
@@ -67,9 +81,51 @@ func ExtractTextFromFile(ctx context.Context, fileContent []byte, fileExt string
 		span.SetTag("error", "true")
 		span.SetData("error_message", err.Error())
 		span.Status = sentry.SpanStatusAborted
-		return "", fmt.Errorf("file processing failed: %w", err)
+		return "", models.ExtractionReport{}, fmt.Errorf("file processing failed: %w", err)
 	}
 	utils.Logger.Printf("File processing completed in %v", duration)
 	span.SetData("extracted_text_length", len(extractedText))
-	return extractedText, nil
+	span.SetData("extraction_pages", report.Pages)
+	span.SetData("extraction_ocr_used", report.OCRUsed)
+	span.SetData("extraction_language", report.Language)
+	span.SetData("extraction_quality_score", report.QualityScore)
+
+	storeCachedExtraction(ctx, userID, cacheKey, extractedText, report)
+	return extractedText, report, nil
+}
+
+// lookupCachedExtraction returns a previous extraction of identical content, if any.
+// Cache failures are logged and treated as misses so extraction can proceed.
+func lookupCachedExtraction(ctx context.Context, userID, key string) *models.CachedExtraction {
+	if extractionCache == nil || userID == "" {
+		return nil
+	}
+	cached, err := extractionCache.Get(ctx, userID, key)
+	if err != nil {
+		utils.Logger.Printf("Extraction cache lookup failed for %s: %v", key, err)
+		return nil
+	}
+	if cached == nil || cached.ExtractorVersion != processors.ExtractorVersion {
+		return nil
+	}
+	return cached
+}
+
+// storeCachedExtraction saves an extraction result for reuse; failures are logged only.
+func storeCachedExtraction(ctx context.Context, userID, key, text string, report models.ExtractionReport) {
+	if extractionCache == nil || userID == "" || len(text) > maxCachedTextBytes {
+		return
+	}
+	now := time.Now()
+	entry := &models.CachedExtraction{
+		Text:             text,
+		Report:           report,
+		ExtractorVersion: processors.ExtractorVersion,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(extractionCacheTTL),
+	}
+	if err := extractionCache.Set(ctx, userID, key, entry); err != nil {
+		utils.Logger.Printf("Failed to store extraction cache entry %s: %v", key, err)
+		sentry.CaptureException(fmt.Errorf("extraction cache write failed: %w", err))
+	}
 }
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"easy-apply/models"
+	"easy-apply/processors"
+	"easy-apply/utils"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Job page cache freshness. Within jobPageFreshFor a cached page is served
+// as-is; after that it is revalidated with a conditional request, and after
+// jobPageMaxAge it is discarded and scraped again.
+const (
+	jobPageFreshFor = time.Hour
+	jobPageMaxAge   = 24 * time.Hour
+)
+
+// JobPageCache stores scraped job postings keyed by normalised URL.
+// Get returns nil without an error on a cache miss.
+type JobPageCache interface {
+	Get(ctx context.Context, key string) (*models.CachedJobPage, error)
+	Set(ctx context.Context, key string, page *models.CachedJobPage) error
+}
+
+var jobPageCache JobPageCache
+
+// InitializeJobPageCache sets the store used to avoid re-scraping popular job postings.
+func InitializeJobPageCache(cache JobPageCache) {
+	jobPageCache = cache
+	utils.Logger.Println("Processor service initialized with job page cache.")
+}
+
+// trackingParams are query parameters that never change the page content
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true,
+}
+
+// NormalizeJobURL canonicalises a job link so trivially different forms of
+// the same advert share a cache entry: lower-case scheme and host, no default
+// port, fragment or trailing slash, tracking parameters removed and the rest sorted.
+func NormalizeJobURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return strings.TrimSpace(rawURL)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = strings.TrimPrefix(host, "www.")
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+	}
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for i, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for j, v := range values {
+			if i > 0 || j > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(url.QueryEscape(key) + "=" + url.QueryEscape(v))
+		}
+	}
+	u.RawQuery = sb.String()
+	return u.String()
+}
+
+// JobPageCacheKey derives the cache key for a job link from the SHA-256 of its normalised
+// form, the web extractor version and the output format, so pages scraped by older
+// extraction code or in another format are never served.
+func JobPageCacheKey(rawURL string, format processors.TextFormat) string {
+	sum := sha256.Sum256([]byte(NormalizeJobURL(rawURL)))
+	return hex.EncodeToString(sum[:]) + "-v" + processors.WebExtractorVersion + "-" + string(format)
+}
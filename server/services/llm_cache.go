@@ -0,0 +1,55 @@
+package services
+
+import (
+	"easy-apply/processors"
+	"easy-apply/utils"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultLLMCacheDir holds the disk cache when LLM_CACHE_DIR is unset
+const defaultLLMCacheDir = ".cache/llm"
+
+// InitializeLLMCache sets the LLM response cache from LLM_CACHE_BACKEND:
+// "firestore" (the default) uses the given shared store, "disk" a local
+// directory (LLM_CACHE_DIR), "memory" the process only and "off" disables
+// caching. Persistent backends sit behind an in-memory tier. LLM_CACHE_MAX_ITEMS
+// bounds the memory and disk tiers; the Firestore store prunes itself.
+func InitializeLLMCache(firestoreCache processors.LLMCache) {
+	maxItems, _ := strconv.Atoi(os.Getenv("LLM_CACHE_MAX_ITEMS"))
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_CACHE_BACKEND")))
+	if backend == "" {
+		backend = "firestore"
+	}
+
+	var cache processors.LLMCache
+	switch backend {
+	case "off":
+		processors.InitializeLLMCache(nil)
+		utils.Logger.Println("LLM response cache disabled.")
+		return
+	case "memory":
+		cache = processors.NewMemoryLLMCache(maxItems)
+	case "disk":
+		dir := os.Getenv("LLM_CACHE_DIR")
+		if dir == "" {
+			dir = defaultLLMCacheDir
+		}
+		disk, err := processors.NewDiskLLMCache(dir, maxItems)
+		if err != nil {
+			utils.Logger.Printf("Could not open LLM disk cache in %s, falling back to memory: %v", dir, err)
+			cache = processors.NewMemoryLLMCache(maxItems)
+			break
+		}
+		cache = processors.NewTieredLLMCache(processors.NewMemoryLLMCache(maxItems), disk)
+	case "firestore":
+		cache = processors.NewTieredLLMCache(processors.NewMemoryLLMCache(maxItems), firestoreCache)
+	default:
+		utils.Logger.Printf("Unknown LLM_CACHE_BACKEND %q, using memory", backend)
+		backend = "memory"
+		cache = processors.NewMemoryLLMCache(maxItems)
+	}
+	processors.InitializeLLMCache(cache)
+	utils.Logger.Printf("LLM response cache initialized with %s backend.", backend)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"easy-apply/models"     // For models.Colors, models.RecommendationResult
 	"easy-apply/processors" // Assuming this is the correct path to your processors package
-	"easy-apply/utils"      // For utils.Logger
+	"easy-apply/prompts"
+	"easy-apply/utils" // For utils.Logger
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -14,16 +16,70 @@ import (
 	"github.com/getsentry/sentry-go"
 )
 
-var openAIProcessor *processors.OpenAIProcessor
+var (
+	openAIProcessor  *processors.OpenAIProcessor
+	piiRedactor      *processors.PIIRedactor
+	fabricationGuard *processors.FabricationGuard
+)
+
+// generatedDocuments is the JSON reply of a resume generation
+type generatedDocuments struct {
+	ProcessedResume      string `json:"generated_resume"`
+	ProcessedCoverLetter string `json:"generated_cover_letter"`
+}
 
 // InitializeOpenAIService sets up the necessary components for the OpenAI service.
 func InitializeOpenAIService(oap *processors.OpenAIProcessor) {
 	openAIProcessor = oap
-	utils.Logger.Println("OpenAIService initialized with OpenAIProcessor.")
+	piiRedactor = processors.NewPIIRedactorFromEnv()
+	fabricationGuard = processors.NewFabricationGuardFromEnv()
+	utils.Logger.Printf("OpenAIService initialized with OpenAIProcessor (PII redaction enabled: %v, fabrication guard enabled: %v, strict: %v).", piiRedactor.Enabled, fabricationGuard.Enabled, fabricationGuard.Strict)
+}
+
+// redactResume strips PII from resume text before it is sent to an LLM provider
+// and records what was redacted on the span.
+func redactResume(span *sentry.Span, resumeText string) (string, *processors.Redaction, models.RedactionAudit) {
+	redactedText, redaction := piiRedactor.Redact(resumeText)
+	audit := redaction.Audit(piiRedactor != nil && piiRedactor.Enabled)
+	span.SetData("pii_redaction_enabled", audit.Enabled)
+	span.SetData("pii_redacted_values", len(audit.Entries))
+	return redactedText, redaction, audit
+}
+
+// recordLLMError tags span with the schema and violations when err is a *processors.SchemaError
+func recordLLMError(span *sentry.Span, err error) {
+	var schemaErr *processors.SchemaError
+	if !errors.As(err, &schemaErr) {
+		return
+	}
+	span.SetTag("llm_schema_violation", "true")
+	span.SetData("llm_schema", schemaErr.Schema)
+	span.SetData("llm_schema_attempts", schemaErr.Attempts)
+	span.SetData("llm_schema_violations", schemaErr.Violations)
+}
+
+// GenerationOptions are the inputs of ProcessWithOpenAI.
+type GenerationOptions struct {
+	JobPosting   string
+	Resume       string // the extracted (or user-confirmed) resume text
+	TemplateHTML string
+	Colors       models.Colors
+	// KnownJob carries fields already found on the job page; with a title and
+	// company the job details LLM call is skipped
+	KnownJob models.JobPostingFields
+	// Languages are the detected languages; both documents are written in Languages.Output
+	Languages models.LanguageInfo
+	// StrictFacts revises and strips unsupported claims instead of only flagging them
+	StrictFacts bool
+	// OnDelta, when set, receives partial resume and cover letter text while generation runs
+	OnDelta DocumentDeltaFunc
 }
 
 // ProcessWithOpenAI handles interactions with OpenAI for document processing and job detail extraction.
-func ProcessWithOpenAI(ctx context.Context, jobPosting, extractedResume, selectedTemplateHTML string, selectedColors models.Colors) (processedDocs map[string]string, jobDetails map[string]string, err error) {
+// PII in the resume is replaced with placeholders before the call and restored in the generated documents.
+// The generated resume is checked against the original for unsupported claims; with StrictFacts
+// (or FABRICATION_GUARD_STRICT) a resume with flags is revised once and what remains is stripped.
+func ProcessWithOpenAI(ctx context.Context, opts GenerationOptions) (result *models.GenerationResult, err error) {
 	parentSpan := sentry.SpanFromContext(ctx)
 	var span *sentry.Span
 	if parentSpan != nil {
@@ -40,32 +96,29 @@ func ProcessWithOpenAI(ctx context.Context, jobPosting, extractedResume, selecte
 		span.Finish()
 	}()
 
-	span.SetData("job_posting_length", len(jobPosting))
-	span.SetData("extracted_resume_length", len(extractedResume))
-	// Note: selectedTemplateHTML and selectedColors are not directly used in the original OpenAI calls in upload.go's processWithOpenAI
+	span.SetData("job_posting_length", len(opts.JobPosting))
+	span.SetData("extracted_resume_length", len(opts.Resume))
+	span.SetData("output_language", opts.Languages.Output)
 
 	if openAIProcessor == nil {
 		err = fmt.Errorf("OpenAIProcessor not initialized in openai_service")
 		utils.Logger.Println(err.Error())
-		return nil, nil, err
+		return nil, err
 	}
 
-	colors := fmt.Sprintf(
-		"<color_palette>\n:primary %s\n:secondary %s\n:accent %s\n:text %s\n",
-		selectedColors.Primary,
-		selectedColors.Secondary,
-		selectedColors.Accent,
-		selectedColors.Text,
-	)
+	redactedResume, redaction, redactionAudit := redactResume(span, opts.Resume)
 
-	documents := BuildEnhancedUserMessage(jobPosting, extractedResume, selectedTemplateHTML, colors) // Assuming jobTitle and company are not needed here
+	userMessage, err := BuildEnhancedUserMessage(processors.LLMUsageScopeFrom(ctx).UserID, opts.JobPosting, redactedResume, opts.TemplateHTML, opts.Colors, opts.Languages.Output)
+	if err != nil {
+		return nil, err
+	}
+	span.SetData("prompt_version", userMessage.ID())
+	promptVersions := make(map[string]string)
 
 	var (
-		processedDocumentsResult struct {
-			ProcessedResume      string `json:"generated_resume"`
-			ProcessedCoverLetter string `json:"generated_cover_letter"`
-		}
-		jobDetailsResult struct {
+		processedDocumentsResult generatedDocuments
+		generatedJSON            string // the raw reply, still redacted, for a revision
+		jobDetailsResult         struct {
 			Title   string `json:"title"`
 			Company string `json:"company_name"`
 			Source  string `json:"source_site,omitempty"`
@@ -76,20 +129,38 @@ func ProcessWithOpenAI(ctx context.Context, jobPosting, extractedResume, selecte
 		multiErr []error
 	)
 
-	wg.Add(2)
+	knownJobDetails := opts.KnownJob.Title != "" && opts.KnownJob.Company != ""
+	if knownJobDetails {
+		jobDetailsResult.Title = opts.KnownJob.Title
+		jobDetailsResult.Company = opts.KnownJob.Company
+		span.SetData("job_details_extraction_method", opts.KnownJob.ExtractionMethod)
+		wg.Add(1)
+	} else {
+		wg.Add(2)
+	}
+
+	// The stream outlives the generation so a revision can replace what was sent
+	var stream *documentStream
+	if opts.OnDelta != nil {
+		stream = newDocumentStream(opts.OnDelta, redaction)
+	}
 
 	// Process documents (Resume and Cover Letter)
 	go func(gCtx context.Context) {
 		defer wg.Done()
 		taskSpan := sentry.StartSpan(gCtx, "openai.task.generate_resume_cover_letter")
 		defer taskSpan.Finish()
-		taskSpan.SetData("input_documents_length", len(documents))
+		taskSpan.SetData("input_documents_length", len(userMessage.Text))
 
 		utils.Logger.Println("Starting resume and cover letter processing with OpenAI")
 		startTime := time.Now()
 
-		// Assuming ProcessDocuments takes the combined documents string
-		processedDocumentsJSON, procErr := openAIProcessor.ProcessDocuments(documents)
+		var onText func(string)
+		if stream != nil {
+			defer stream.flush()
+			onText = stream.onText
+		}
+		resp, procErr := openAIProcessor.ProcessDocuments(gCtx, userMessage, onText)
 		duration := time.Since(startTime)
 		taskSpan.SetData("duration_ms", duration.Milliseconds())
 
@@ -97,6 +168,7 @@ func ProcessWithOpenAI(ctx context.Context, jobPosting, extractedResume, selecte
 			taskSpan.SetTag("error", "true")
 			taskSpan.SetData("error_message", procErr.Error())
 			taskSpan.Status = sentry.SpanStatusAborted
+			recordLLMError(taskSpan, procErr)
 			utils.Logger.Printf("OpenAI document processing failed after %v: %v", duration, procErr)
 			errsMu.Lock()
 			multiErr = append(multiErr, fmt.Errorf("OpenAI document processing failed: %w", procErr))
@@ -104,10 +176,13 @@ func ProcessWithOpenAI(ctx context.Context, jobPosting, extractedResume, selecte
 			return
 		}
 		utils.Logger.Printf("OpenAI document processing completed in %v", duration)
-		taskSpan.SetData("output_json_length", len(processedDocumentsJSON))
+		taskSpan.SetData("output_json_length", len(resp.Text))
+		taskSpan.SetData("prompt_version", resp.PromptVersion)
 
 		mu.Lock()
-		unmarshalErr := json.Unmarshal([]byte(processedDocumentsJSON), &processedDocumentsResult)
+		promptVersions[string(processors.LLMTaskResumeGeneration)] = resp.PromptVersion
+		generatedJSON = resp.Text
+		unmarshalErr := json.Unmarshal([]byte(resp.Text), &processedDocumentsResult)
 		mu.Unlock()
 		if unmarshalErr != nil {
 			taskSpan.SetTag("error", "true")
@@ -120,46 +195,50 @@ func ProcessWithOpenAI(ctx context.Context, jobPosting, extractedResume, selecte
 		}
 	}(sentry.SetHubOnContext(ctx, sentry.CurrentHub().Clone()))
 
-	// Process job details (Title and Company)
-	go func(gCtx context.Context) {
-		defer wg.Done()
-		taskSpan := sentry.StartSpan(gCtx, "openai.task.extract_job_details")
-		defer taskSpan.Finish()
-		taskSpan.SetData("job_posting_length", len(jobPosting))
-
-		utils.Logger.Println("Starting job details processing with OpenAI")
-		startTime := time.Now()
-
-		jobDetailsJSON, procErr := openAIProcessor.GenerateSubjectName(jobPosting) // Assumes this method exists
-		duration := time.Since(startTime)
-		taskSpan.SetData("duration_ms", duration.Milliseconds())
-
-		if procErr != nil {
-			taskSpan.SetTag("error", "true")
-			taskSpan.SetData("error_message", procErr.Error())
-			taskSpan.Status = sentry.SpanStatusAborted
-			utils.Logger.Printf("Job details processing failed after %v: %v", duration, procErr)
-			errsMu.Lock()
-			multiErr = append(multiErr, fmt.Errorf("job details processing failed: %w", procErr))
-			errsMu.Unlock()
-			return
-		}
-		utils.Logger.Printf("Job details processing completed in %v", duration)
-		taskSpan.SetData("output_json_length", len(jobDetailsJSON))
-
-		mu.Lock()
-		unmarshalErr := json.Unmarshal([]byte(jobDetailsJSON), &jobDetailsResult)
-		mu.Unlock()
-		if unmarshalErr != nil {
-			taskSpan.SetTag("error", "true")
-			taskSpan.SetData("unmarshal_error", unmarshalErr.Error())
-			// taskSpan.SetData("raw_json_response", jobDetailsJSON) // Be cautious with PII
-			taskSpan.Status = sentry.SpanStatusInvalidArgument
-			errsMu.Lock()
-			multiErr = append(multiErr, fmt.Errorf("failed to parse job details JSON: %w", unmarshalErr))
-			errsMu.Unlock()
-		}
-	}(sentry.SetHubOnContext(ctx, sentry.CurrentHub().Clone()))
+	// Process job details (Title and Company) unless the page already provided them
+	if !knownJobDetails {
+		go func(gCtx context.Context) {
+			defer wg.Done()
+			taskSpan := sentry.StartSpan(gCtx, "openai.task.extract_job_details")
+			defer taskSpan.Finish()
+			taskSpan.SetData("job_posting_length", len(opts.JobPosting))
+
+			utils.Logger.Println("Starting job details processing with OpenAI")
+			startTime := time.Now()
+
+			resp, procErr := openAIProcessor.GenerateSubjectName(gCtx, opts.JobPosting)
+			duration := time.Since(startTime)
+			taskSpan.SetData("duration_ms", duration.Milliseconds())
+
+			if procErr != nil {
+				taskSpan.SetTag("error", "true")
+				taskSpan.SetData("error_message", procErr.Error())
+				taskSpan.Status = sentry.SpanStatusAborted
+				recordLLMError(taskSpan, procErr)
+				utils.Logger.Printf("Job details processing failed after %v: %v", duration, procErr)
+				errsMu.Lock()
+				multiErr = append(multiErr, fmt.Errorf("job details processing failed: %w", procErr))
+				errsMu.Unlock()
+				return
+			}
+			utils.Logger.Printf("Job details processing completed in %v", duration)
+			taskSpan.SetData("output_json_length", len(resp.Text))
+
+			mu.Lock()
+			promptVersions[string(processors.LLMTaskSubjectExtraction)] = resp.PromptVersion
+			unmarshalErr := json.Unmarshal([]byte(resp.Text), &jobDetailsResult)
+			mu.Unlock()
+			if unmarshalErr != nil {
+				taskSpan.SetTag("error", "true")
+				taskSpan.SetData("unmarshal_error", unmarshalErr.Error())
+				// taskSpan.SetData("raw_json_response", jobDetailsJSON) // Be cautious with PII
+				taskSpan.Status = sentry.SpanStatusInvalidArgument
+				errsMu.Lock()
+				multiErr = append(multiErr, fmt.Errorf("failed to parse job details JSON: %w", unmarshalErr))
+				errsMu.Unlock()
+			}
+		}(sentry.SetHubOnContext(ctx, sentry.CurrentHub().Clone()))
+	}
 
 	wg.Wait()
 
@@ -171,25 +250,95 @@ func ProcessWithOpenAI(ctx context.Context, jobPosting, extractedResume, selecte
 			}
 		}
 		err = multiErr[0] // Set the main error for the defer function
-		return nil, nil, multiErr[0]
+		return nil, multiErr[0]
 	}
 
-	processedDocs = map[string]string{
-		"resume":      processedDocumentsResult.ProcessedResume,
-		"coverLetter": processedDocumentsResult.ProcessedCoverLetter,
+	result = &models.GenerationResult{
+		SourceResume:     opts.Resume,
+		Resume:           redaction.Restore(processedDocumentsResult.ProcessedResume),
+		CoverLetter:      redaction.Restore(processedDocumentsResult.ProcessedCoverLetter),
+		JobTitle:         jobDetailsResult.Title,
+		JobCompany:       jobDetailsResult.Company,
+		JobSource:        jobDetailsResult.Source,
+		ExtractionMethod: opts.KnownJob.ExtractionMethod,
+		Redaction:        redactionAudit,
+		PromptVersions:   promptVersions,
+		Languages:        opts.Languages,
 	}
-	jobDetails = map[string]string{
-		"title":   jobDetailsResult.Title,
-		"company": jobDetailsResult.Company,
-		"source":  jobDetailsResult.Source,
+
+	result.Fabrication = checkFabrication(ctx, result, userMessage, generatedJSON, redaction, stream, opts.StrictFacts)
+	span.SetData("fabrication_flags", len(result.Fabrication.Flags))
+	span.SetData("fabrication_regenerated", result.Fabrication.Regenerated)
+
+	span.SetData("processed_resume_length", len(result.Resume))
+	span.SetData("processed_cover_letter_length", len(result.CoverLetter))
+	span.SetData("extracted_job_title", result.JobTitle)
+	span.SetData("extracted_job_company", result.JobCompany)
+
+	return result, nil
+}
+
+// checkFabrication flags claims in the generated resume that the original does not support.
+// In strict mode a flagged resume is revised once, then claims still unsupported are stripped;
+// result's documents are updated in place and a revision's prompt version is added to its PromptVersions.
+// A failed revision keeps the first resume, so strict mode never fails the upload.
+func checkFabrication(ctx context.Context, result *models.GenerationResult, userMessage prompts.Rendered, generatedJSON string, redaction *processors.Redaction, stream *documentStream, strictFacts bool) models.FabricationReport {
+	span := sentry.StartSpan(ctx, "openai.task.check_fabrication")
+	defer span.Finish()
+
+	report := models.FabricationReport{
+		Enabled: fabricationGuard != nil && fabricationGuard.Enabled,
+		Strict:  fabricationGuard != nil && fabricationGuard.Enabled && (fabricationGuard.Strict || strictFacts),
+	}
+	allowed := []string{result.JobTitle, result.JobCompany}
+	report.Flags = fabricationGuard.Check(result.SourceResume, result.Resume, allowed...)
+	span.SetData("initial_flags", len(report.Flags))
+	if !report.Strict || len(report.Flags) == 0 {
+		return report
 	}
 
-	span.SetData("processed_resume_length", len(processedDocs["resume"]))
-	span.SetData("processed_cover_letter_length", len(processedDocs["coverLetter"]))
-	span.SetData("extracted_job_title", jobDetails["title"])
-	span.SetData("extracted_job_company", jobDetails["company"])
+	claims := make([]string, len(report.Flags))
+	for i, flag := range report.Flags {
+		claims[i] = flag.Claim
+	}
+	var onText func(string)
+	if stream != nil {
+		onText = stream.onText
+	}
+	utils.Logger.Printf("Generated resume has %d unsupported claims, requesting a revision", len(claims))
+	resp, err := openAIProcessor.ReviseDocuments(span.Context(), userMessage, generatedJSON, claims, onText)
+	if stream != nil {
+		stream.flush()
+	}
+	var revised generatedDocuments
+	if err == nil {
+		err = json.Unmarshal([]byte(resp.Text), &revised)
+	}
+	if err == nil && strings.TrimSpace(revised.ProcessedResume) == "" {
+		err = errors.New("revision returned an empty resume")
+	}
+	if err != nil {
+		span.SetTag("error", "true")
+		span.SetData("error_message", err.Error())
+		recordLLMError(span, err)
+		utils.Logger.Printf("Resume revision failed, stripping claims from the first resume: %v", err)
+		sentry.CaptureException(fmt.Errorf("resume revision failed: %w", err))
+	} else {
+		report.Regenerated = true
+		result.PromptVersions["resume_revision"] = resp.PromptVersion
+		result.Resume = redaction.Restore(revised.ProcessedResume)
+		result.CoverLetter = redaction.Restore(revised.ProcessedCoverLetter)
+		report.Flags = fabricationGuard.Check(result.SourceResume, result.Resume, allowed...)
+	}
 
-	return processedDocs, jobDetails, nil
+	if len(report.Flags) > 0 {
+		result.Resume, report.Flags = processors.StripUnsupportedClaims(result.Resume, report.Flags)
+		if stream != nil {
+			stream.replace("resume", result.Resume)
+		}
+	}
+	span.SetData("remaining_flags", len(report.Flags))
+	return report
 }
 
 // AnalyzeResumeForRecommendation processes resume text using OpenAI for job recommendations.
@@ -209,16 +358,19 @@ func AnalyzeResumeForRecommendation(ctx context.Context, resumeText string) (mod
 		return recommendation, err
 	}
 
-	recommendationJSON, err := openAIProcessor.AnalyzeResumeForRecommendation(resumeText) // Assumes this method exists
+	redactedResume, _, _ := redactResume(span, resumeText)
+	resp, err := openAIProcessor.AnalyzeResumeForRecommendation(span.Context(), redactedResume)
 	if err != nil {
 		span.SetTag("error", "true")
 		span.SetData("openai_call_error", err.Error())
 		span.Status = sentry.SpanStatusAborted
+		recordLLMError(span, err)
 		return recommendation, fmt.Errorf("OpenAI analysis for recommendation failed: %w", err)
 	}
-	span.SetData("openai_response_json_length", len(recommendationJSON))
+	span.SetData("openai_response_json_length", len(resp.Text))
+	span.SetData("prompt_version", resp.PromptVersion)
 
-	if err := json.Unmarshal([]byte(recommendationJSON), &recommendation); err != nil {
+	if err := json.Unmarshal([]byte(resp.Text), &recommendation); err != nil {
 		span.SetTag("error", "true")
 		span.SetData("unmarshal_error", err.Error())
 		// span.SetData("raw_json_response", recommendationJSON) // Be cautious with PII
@@ -231,33 +383,34 @@ func AnalyzeResumeForRecommendation(ctx context.Context, resumeText string) (mod
 	return recommendation, nil
 }
 
-func BuildEnhancedUserMessage(jobPosting, extractedResume, selectedTemplate, selectedColors string) string {
-	var builder strings.Builder
-
-	builder.WriteString("<analysis_request>\n")
-
-	builder.WriteString("<job_description>\n")
-	builder.WriteString(strings.TrimSpace(jobPosting))
-	builder.WriteString("\n</job_description>\n\n")
-
-	builder.WriteString("<current_resume>\n")
-	builder.WriteString(strings.TrimSpace(extractedResume))
-	builder.WriteString("\n</current_resume>\n\n")
-
-	builder.WriteString("<selectedTemplate>\n")
-	builder.WriteString(strings.TrimSpace(selectedTemplate))
-	builder.WriteString("\n</selectedTemplate>\n\n")
-
-	builder.WriteString("<selectedColors\n")
-	builder.WriteString(strings.TrimSpace(selectedColors))
-	builder.WriteString("\n</selectedColors\n\n")
-
-	builder.WriteString("<task>\n")
-	builder.WriteString("Optimize this resume for the job description above. ")
-	builder.WriteString("Follow all guidelines in your system instructions, ")
-	builder.WriteString("ensuring ATS compatibility and keyword optimization.")
-	builder.WriteString("\n</task>\n")
-	builder.WriteString("</analysis_request>")
+// DetectLanguages detects the languages of the job posting and resume and picks the output
+// language: requested (a code or name) when set, otherwise the posting's, then the resume's,
+// then English. An unsupported requested language returns processors.ErrUnsupportedLanguage.
+func DetectLanguages(requested, jobPosting, resumeText string) (models.LanguageInfo, error) {
+	languages := models.LanguageInfo{
+		Posting: processors.DetectLanguage(jobPosting),
+		Resume:  processors.DetectLanguage(resumeText),
+	}
+	output, err := processors.OutputLanguage(requested, languages.Posting, languages.Resume)
+	if err != nil {
+		return languages, err
+	}
+	languages.Output = output
+	return languages, nil
+}
 
-	return builder.String()
+// BuildEnhancedUserMessage renders the resume generation request from the
+// prompts.ResumeUser template, A/B assigned by subject (the user ID).
+// outputLanguage is a language code.
+func BuildEnhancedUserMessage(subject, jobPosting, extractedResume, selectedTemplate string, selectedColors models.Colors, outputLanguage string) (prompts.Rendered, error) {
+	return prompts.Render(prompts.ResumeUser, subject, prompts.Vars{
+		"jobPosting":     strings.TrimSpace(jobPosting),
+		"resume":         strings.TrimSpace(extractedResume),
+		"template":       strings.TrimSpace(selectedTemplate),
+		"colorPrimary":   selectedColors.Primary,
+		"colorSecondary": selectedColors.Secondary,
+		"colorAccent":    selectedColors.Accent,
+		"colorText":      selectedColors.Text,
+		"language":       processors.LanguageName(outputLanguage),
+	})
 }
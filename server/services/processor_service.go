@@ -46,8 +46,10 @@ func InitProcessors() (*processors.FileProcessor, *processors.OpenAIProcessor, e
 }
 
 // ProcessFileAndWeb concurrently processes a file and a web link.
-// It uses the initialized processors from this package.
-func ProcessFileAndWeb(ctx context.Context, fileContent []byte, fileExt, webLink string) (*models.ProcessingResult, error) {
+// It uses the initialized processors from this package. refreshJobPage skips
+// the job page cache, for when the user reports that the posting has changed.
+// The file's extraction is cached for userID.
+func ProcessFileAndWeb(ctx context.Context, userID string, fileContent []byte, fileExt, webLink string, refreshJobPage bool) (*models.ProcessingResult, error) {
 	parentSpan := sentry.SpanFromContext(ctx)
 	var span *sentry.Span
 	if parentSpan != nil {
@@ -85,7 +87,7 @@ func ProcessFileAndWeb(ctx context.Context, fileContent []byte, fileExt, webLink
 		// This requires file_service's fileProcessor to be initialized correctly.
 		// Alternatively, pass localFileProcessor here or make ExtractTextFromFile accept a processor.
 		// For now, assuming file_service.ExtractTextFromFile is the intended way.
-		extractedText, taskErr := ExtractTextFromFile(gCtx, fileContent, fileExt) // From this (services) package
+		extractedText, report, taskErr := ExtractTextFromFile(gCtx, userID, fileContent, fileExt) // From this (services) package
 
 		mu.Lock()
 		defer mu.Unlock()
@@ -99,6 +101,7 @@ func ProcessFileAndWeb(ctx context.Context, fileContent []byte, fileExt, webLink
 			errs <- wrappedErr
 		} else {
 			result.ExtractedResume = extractedText
+			result.ExtractionReport = report
 			taskSpan.SetData("extracted_resume_length", len(extractedText))
 		}
 	}(sentry.SetHubOnContext(ctx, sentry.CurrentHub().Clone()))
@@ -109,10 +112,11 @@ func ProcessFileAndWeb(ctx context.Context, fileContent []byte, fileExt, webLink
 		taskSpan := sentry.StartSpan(gCtx, "task.scrape_web_link_async_service")
 		defer taskSpan.Finish()
 		taskSpan.SetData("web_link", webLink)
+		taskSpan.SetData("refresh_job_page", refreshJobPage)
 		utils.Logger.Println("Starting web link processing in goroutine (processor_service)")
 		webStart := time.Now()
 
-		scrappedContent, taskErr := localWebProcessor.ProcessWebLink(webLink) // Assumes ProcessWebLink method
+		scrappedContent, jobFields, taskErr := scrapeJobPage(gCtx, taskSpan, webLink, refreshJobPage)
 		duration := time.Since(webStart)
 		taskSpan.SetData("duration_ms", duration.Milliseconds())
 
@@ -130,7 +134,9 @@ func ProcessFileAndWeb(ctx context.Context, fileContent []byte, fileExt, webLink
 			errs <- wrappedErr
 		} else {
 			result.ScrappedWebJobPosting = scrappedContent
+			result.JobPostingFields = jobFields
 			taskSpan.SetData("scrapped_content_length", len(scrappedContent))
+			taskSpan.SetData("job_posting_extraction_method", jobFields.ExtractionMethod)
 			utils.Logger.Printf("Web processing completed in %v", duration)
 		}
 	}(sentry.SetHubOnContext(ctx, sentry.CurrentHub().Clone()))
@@ -159,3 +165,78 @@ func ProcessFileAndWeb(ctx context.Context, fileContent []byte, fileExt, webLink
 	span.SetData("scrapped_web_job_posting_length", len(result.ScrappedWebJobPosting))
 	return &result, nil
 }
+
+// scrapeJobPage returns the job posting at webLink, serving it from the job
+// page cache while fresh and revalidating it with a conditional request once
+// stale. Cache failures are logged and the page is scraped as normal.
+func scrapeJobPage(ctx context.Context, span *sentry.Span, webLink string, bypassCache bool) (string, models.JobPostingFields, error) {
+	if jobPageCache == nil {
+		span.SetTag("job_page_cache", "disabled")
+		return localWebProcessor.ProcessWebLink(ctx, webLink)
+	}
+
+	key := JobPageCacheKey(webLink, localWebProcessor.Format)
+	var cached *models.CachedJobPage
+	if bypassCache {
+		span.SetTag("job_page_cache", "bypass")
+	} else {
+		var err error
+		cached, err = jobPageCache.Get(ctx, key)
+		if err != nil {
+			utils.Logger.Printf("Job page cache lookup failed for %s: %v", webLink, err)
+			cached = nil
+		}
+		if cached != nil && time.Since(cached.FetchedAt) > jobPageMaxAge {
+			cached = nil
+		}
+		if cached != nil && time.Since(cached.ValidatedAt) < jobPageFreshFor {
+			span.SetTag("job_page_cache", "hit")
+			return cached.Text, cached.Fields, nil
+		}
+	}
+
+	var etag, lastModified string
+	if cached != nil {
+		etag, lastModified = cached.ETag, cached.LastModified
+	}
+	page, err := localWebProcessor.FetchJobPage(ctx, webLink, etag, lastModified)
+	if err != nil {
+		return "", models.JobPostingFields{}, err
+	}
+
+	now := time.Now()
+	if page.NotModified && cached != nil {
+		span.SetTag("job_page_cache", "revalidated")
+		cached.ValidatedAt = now
+		storeCachedJobPage(ctx, key, cached)
+		return cached.Text, cached.Fields, nil
+	}
+
+	if cached != nil {
+		span.SetTag("job_page_cache", "changed")
+	} else if !bypassCache {
+		span.SetTag("job_page_cache", "miss")
+	}
+	storeCachedJobPage(ctx, key, &models.CachedJobPage{
+		URL:          NormalizeJobURL(webLink),
+		Text:         page.Text,
+		Fields:       page.Fields,
+		ETag:         page.ETag,
+		LastModified: page.LastModified,
+		FetchedAt:    now,
+		ValidatedAt:  now,
+		ExpiresAt:    now.Add(jobPageMaxAge),
+	})
+	return page.Text, page.Fields, nil
+}
+
+// storeCachedJobPage saves a scraped page for reuse; failures are logged only.
+func storeCachedJobPage(ctx context.Context, key string, page *models.CachedJobPage) {
+	if len(page.Text)+len(page.Fields.Description) > maxCachedTextBytes {
+		return
+	}
+	if err := jobPageCache.Set(ctx, key, page); err != nil {
+		utils.Logger.Printf("Failed to store job page cache entry for %s: %v", page.URL, err)
+		sentry.CaptureException(fmt.Errorf("job page cache write failed: %w", err))
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"easy-apply/models"
+	"easy-apply/processors"
+	"easy-apply/utils"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// usageWriteTimeout bounds the background write of one ledger record
+const usageWriteTimeout = 10 * time.Second
+
+// Dimensions a usage report can be grouped by
+const (
+	UsageGroupDay   = "day"
+	UsageGroupUser  = "user"
+	UsageGroupTask  = "task"
+	UsageGroupModel = "model"
+)
+
+// ErrInvalidUsageGroup is returned for an unknown groupBy dimension
+var ErrInvalidUsageGroup = errors.New("invalid usage group")
+
+// UsageLedger stores LLM usage records.
+type UsageLedger interface {
+	Record(ctx context.Context, record models.LLMUsageRecord) error
+	List(ctx context.Context, query models.UsageQuery) ([]models.LLMUsageRecord, error)
+}
+
+var usageLedger UsageLedger
+
+// ledgerRecorder writes router usage records to the ledger without
+// holding up the LLM call
+type ledgerRecorder struct {
+	ledger UsageLedger
+}
+
+func (r ledgerRecorder) RecordLLMUsage(ctx context.Context, record models.LLMUsageRecord) {
+	go func() {
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), usageWriteTimeout)
+		defer cancel()
+		if err := r.ledger.Record(writeCtx, record); err != nil {
+			utils.Logger.Printf("Failed to record LLM usage for task %s: %v", record.Task, err)
+		}
+	}()
+}
+
+// InitializeUsageLedger sets the ledger and records every LLM call to it.
+func InitializeUsageLedger(ledger UsageLedger) {
+	usageLedger = ledger
+	processors.InitializeLLMUsageRecorder(ledgerRecorder{ledger: ledger})
+	utils.Logger.Println("LLM usage ledger initialized.")
+}
+
+// SummarizeUsage aggregates the ledger records matching query by the given
+// dimensions (day, user, task, model). Rows are ordered by their dimensions.
+func SummarizeUsage(ctx context.Context, query models.UsageQuery, groupBy []string) ([]models.UsageSummaryRow, error) {
+	if usageLedger == nil {
+		return nil, fmt.Errorf("usage ledger not initialized")
+	}
+	group := make(map[string]bool, len(groupBy))
+	for _, dimension := range groupBy {
+		switch dimension {
+		case UsageGroupDay, UsageGroupUser, UsageGroupTask, UsageGroupModel:
+			group[dimension] = true
+		default:
+			return nil, fmt.Errorf("%w %q", ErrInvalidUsageGroup, dimension)
+		}
+	}
+
+	records, err := usageLedger.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[models.UsageSummaryRow]*models.UsageSummaryRow)
+	latency := make(map[models.UsageSummaryRow]int64)
+	for _, record := range records {
+		var key models.UsageSummaryRow
+		if group[UsageGroupDay] {
+			key.Day = record.CreatedAt.UTC().Format("2006-01-02")
+		}
+		if group[UsageGroupUser] {
+			key.UserID = record.UserID
+		}
+		if group[UsageGroupTask] {
+			key.Task = record.Task
+		}
+		if group[UsageGroupModel] {
+			key.Model = record.Model
+		}
+
+		row, ok := rows[key]
+		if !ok {
+			row = &models.UsageSummaryRow{Day: key.Day, UserID: key.UserID, Task: key.Task, Model: key.Model}
+			rows[key] = row
+		}
+		row.Calls++
+		if !record.Success {
+			row.FailedCalls++
+		}
+		row.PromptTokens += record.PromptTokens
+		row.CompletionTokens += record.CompletionTokens
+		row.TotalTokens += record.TotalTokens
+		row.CostUSD += record.CostUSD
+		latency[key] += record.LatencyMs
+	}
+
+	summary := make([]models.UsageSummaryRow, 0, len(rows))
+	for key, row := range rows {
+		row.AvgLatencyMs = latency[key] / int64(row.Calls)
+		summary = append(summary, *row)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		a, b := summary[i], summary[j]
+		return strings.Join([]string{a.Day, a.UserID, a.Task, a.Model}, "\x00") <
+			strings.Join([]string{b.Day, b.UserID, b.Task, b.Model}, "\x00")
+	})
+	return summary, nil
+}
@@ -27,18 +27,25 @@ var (
 // ProgressUpdate defines the JSON structure for messages sent over SSE
 // Exported for reuse
 type ProgressUpdate struct {
-	Step    string `json:"step"`
-	Status  string `json:"status"`
-	Message string `json:"message,omitempty"`
+	Step    string      `json:"step"`
+	Status  string      `json:"status"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 // SendProgress creates a JSON payload and sends it to the specified SSE channel
 func SendProgress(channelID, step, status, message string) {
+	SendProgressWithData(channelID, step, status, message, nil)
+}
+
+// SendProgressWithData sends a progress update carrying a structured payload, such as an extraction report.
+// Only the step and status of such updates are logged.
+func SendProgressWithData(channelID, step, status, message string, data interface{}) {
 	if channelID == "" {
 		return
 	}
 
-	update := ProgressUpdate{Step: step, Status: status, Message: message}
+	update := ProgressUpdate{Step: step, Status: status, Message: message, Data: data}
 	jsonData, err := json.Marshal(update)
 	if err != nil {
 		utils.Logger.Printf("Error marshalling progress update: %v", err)
@@ -57,7 +64,12 @@ func SendProgress(channelID, step, status, message string) {
 	// Non-blocking send with timeout
 	select {
 	case client.Channel <- string(jsonData):
-		utils.Logger.Printf("Sent progress for %s: %s", channelID, string(jsonData))
+		// Payloads can hold generated documents with the user's details restored, so they are never logged
+		if data != nil {
+			utils.Logger.Printf("Sent progress for %s: step=%s status=%s (with data)", channelID, step, status)
+		} else {
+			utils.Logger.Printf("Sent progress for %s: %s", channelID, string(jsonData))
+		}
 	case <-time.After(5 * time.Second):
 		utils.Logger.Printf("Progress send timeout for channel %s", channelID)
 	default:
@@ -10,8 +10,11 @@ import (
 	"strings"
 	"time"
 
+	"easy-apply/processors"
+
 	"cloud.google.com/go/firestore"
 	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
 	// To handle potential "status" and "codes" if they were used in the commented-out saveJobs:
 	// "google.golang.org/grpc/status"
 	// "google.golang.org/grpc/codes"
@@ -217,6 +220,20 @@ func uploadJobListings(ctx context.Context, jobListings []JobListing) error {
 		jobsBySource[sourceName] = append(jobsBySource[sourceName], job)
 	}
 
+	// Listing parsing runs on whichever provider LLM_LISTING_PARSING_PROVIDER selects,
+	// and its token usage is recorded against this run
+	llm, err := processors.NewLLMRouterFromEnv(ctx, processors.LLMTaskListingParsing)
+	if err != nil {
+		uploadTx.Status = sentry.SpanStatusInternalError
+		uploadTx.SetTag("error", "true")
+		sentry.CaptureException(err)
+		return fmt.Errorf("failed to initialize LLM provider for listing parsing: %w", err)
+	}
+	runID := uuid.NewString()
+	uploadTx.SetTag("run_id", runID)
+	log.Printf("Scraper run %s: parsing listings with %s", runID, llm.Config(processors.LLMTaskListingParsing).Model)
+	usageCtx := processors.WithLLMUsageScope(ctx, processors.LLMUsageScope{RunID: runID, Endpoint: "scraper"})
+
 	bw := firestoreClient.BulkWriter(currentContext) // Pass context to BulkWriter if its API supports it (check SDK)
 	var totalJobsQueued int
 	var overallUploadError error // To track if any critical error occurs during the loop
@@ -244,15 +261,7 @@ func uploadJobListings(ctx context.Context, jobListings []JobListing) error {
 				continue
 			}
 
-			// Initialize Gemini client once
-			geminiClient, err := InitializeGeminiClient(ctx)
-			if err != nil {
-				log.Fatalf("Failed to initialize Gemini client: %v", err)
-			}
-			defer geminiClient.Close()
-
-			//
-			parsedDetails, err := ParseJobDescription(ctx, geminiClient, job.JobDescription) // ParseJobDescription now starts its own span
+			parsedDetails, err := ParseJobDescription(usageCtx, llm, job.JobDescription) // ParseJobDescription now starts its own span
 			if err != nil {
 				log.Printf("Failed to parse job description for job (Link: %s), skipping: %v", job.Link, err)
 				sentry.WithScope(func(scope *sentry.Scope) {
@@ -321,6 +330,8 @@ func uploadJobListings(ctx context.Context, jobListings []JobListing) error {
 				"tags":                   parsedDetails.Tags,
 				"industry":               parsedDetails.Industry,
 				"domain":                 parsedDetails.Domain,
+				"promptVersion":          parsedDetails.PromptVersion,
+				"language":               parsedDetails.Language,
 			}
 			if parsedDetails.ResponsibleFor == nil || (fmt.Sprintf("%v", parsedDetails.ResponsibleFor) == "[]") {
 				delete(docData, "responsibleFor")
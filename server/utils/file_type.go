@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// MIME types reported for the supported upload formats
+const (
+	MIMETypePDF  = "application/pdf"
+	MIMETypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	MIMETypeText = "text/plain"
+)
+
+// FileType describes the format detected from a file's content.
+type FileType struct {
+	Extension string `json:"extension"` // canonical extension used to pick an extractor, e.g. ".pdf"
+	MIMEType  string `json:"mimeType"`
+}
+
+// fileTypeNames maps canonical extensions to names used in user-facing messages.
+var fileTypeNames = map[string]string{
+	".pdf":  "PDF",
+	".docx": "DOCX",
+	".txt":  "plain text",
+}
+
+// DetectFileType identifies the format of content from its magic bytes,
+// ignoring the filename entirely.
+func DetectFileType(content []byte) (FileType, error) {
+	switch {
+	case bytes.HasPrefix(content, []byte("%PDF-")):
+		return FileType{Extension: ".pdf", MIMEType: MIMETypePDF}, nil
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")):
+		if isDOCXArchive(content) {
+			return FileType{Extension: ".docx", MIMEType: MIMETypeDOCX}, nil
+		}
+		return FileType{}, fmt.Errorf("file is a ZIP archive but not a Word document")
+	case bytes.HasPrefix(content, []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1")):
+		return FileType{}, fmt.Errorf("file is a legacy Word (.doc) document; please save it as DOCX or PDF")
+	}
+
+	mimeType := http.DetectContentType(content)
+	if strings.HasPrefix(mimeType, "text/plain") && utf8.Valid(content) {
+		return FileType{Extension: ".txt", MIMEType: MIMETypeText}, nil
+	}
+	return FileType{}, fmt.Errorf("unrecognised file content (detected %s)", mimeType)
+}
+
+// ValidateFileContent detects the real format of an upload and checks that it
+// is supported and agrees with the extension of the submitted filename.
+func ValidateFileContent(filename string, content []byte) (FileType, error) {
+	if len(content) == 0 {
+		return FileType{}, fmt.Errorf("uploaded file is empty")
+	}
+
+	detected, err := DetectFileType(content)
+	if err != nil {
+		return FileType{}, fmt.Errorf("unsupported file content: %v. Only PDF, DOCX, and TXT files are supported", err)
+	}
+	if !SupportedFileTypes[detected.Extension] {
+		return FileType{}, fmt.Errorf("unsupported file type: %s. Only PDF, DOCX, and TXT files are supported", detected.Extension)
+	}
+
+	fileExt := strings.ToLower(filepath.Ext(filename))
+	if fileExt != detected.Extension {
+		return FileType{}, fmt.Errorf("file extension %q does not match its content, which looks like a %s file. Please rename the file to %s or upload the original document",
+			fileExt, fileTypeNames[detected.Extension], strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))+detected.Extension)
+	}
+
+	return detected, nil
+}
+
+// isDOCXArchive reports whether a ZIP archive contains a Word main document part.
+func isDOCXArchive(content []byte) bool {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			return true
+		}
+	}
+	return false
+}
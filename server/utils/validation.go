@@ -3,8 +3,6 @@ package utils
 import (
 	"easy-apply/models" // Assuming models are in this path
 	"errors"
-	"fmt"
-	"path/filepath"
 	"strings"
 )
 
@@ -15,15 +13,6 @@ var SupportedFileTypes = map[string]bool{
 	".txt":  true,
 }
 
-// ValidateFileType checks if the uploaded file type is supported.
-func ValidateFileType(filename string) error {
-	fileExt := strings.ToLower(filepath.Ext(filename))
-	if !SupportedFileTypes[fileExt] {
-		return fmt.Errorf("unsupported file type: %s. Only PDF, DOCX, and TXT files are supported", fileExt)
-	}
-	return nil
-}
-
 // ValidateUploadRequest validates the parameters for a file upload request.
 func ValidateUploadRequest(userID, webLink string) error {
 	if strings.TrimSpace(userID) == "" {